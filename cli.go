@@ -0,0 +1,817 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/james-see/gofindpi/data"
+	"github.com/james-see/gofindpi/history"
+	"github.com/james-see/gofindpi/scanner"
+)
+
+// scanOptions holds everything the scan command can be told from the command line.
+// Zero values mean "use the built-in default".
+type scanOptions struct {
+	iface       string
+	targets     string
+	exclude     string
+	maxHosts    int
+	family      string
+	probes      []string
+	resolvers   []string
+	dnsServer   string
+	dnsTimeout  time.Duration
+	resolveTime time.Duration
+	tcpPorts    []int
+	timeout     time.Duration
+	scanTimeout time.Duration
+	pingCount   int
+	concurrency int
+	formats     []string
+	outputDir   string
+	resolve     bool
+	fingerprint bool
+	mdns        bool
+	ssdp        bool
+	interactive bool
+	rules       *scanner.RuleSet
+	leases      []scanner.Lease
+	offline     bool
+	history     string
+	find        []scanner.DeviceFamily
+}
+
+// errUsage signals that usage has already been printed and the command should exit non-zero
+var errUsage = errors.New("usage error")
+
+// printUsage prints the top-level command overview
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: gofindpi [command] [flags]
+
+Commands:
+  scan       Scan a local network and identify devices (default)
+  oui        Look up the manufacturer for one or more MAC addresses
+             (oui update --from <file> installs a newer OUI database)
+  history    Show when devices were first and last seen, and their addresses
+  diff       Show new, missing and changed devices between two JSON scans
+  version    Print version information
+  help       Show this help
+
+Run 'gofindpi <command> -h' for command-specific flags.
+`)
+}
+
+// run dispatches to the requested subcommand and returns the process exit code
+func run(args []string) int {
+	cmd := "scan"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	if cmd == "scan" || cmd == "oui" {
+		loadOUIOverride()
+	}
+
+	var err error
+	switch cmd {
+	case "scan":
+		// Keep the historical --version/-v shortcut working
+		if len(args) > 0 && (args[0] == "--version" || args[0] == "-v") {
+			printVersion()
+			return 0
+		}
+		err = runScanCommand(args)
+	case "oui":
+		err = runOUICommand(args)
+	case "history":
+		err = runHistoryCommand(args)
+	case "diff":
+		err = runDiffCommand(args)
+	case "version":
+		printVersion()
+	case "help", "-h", "--help":
+		printUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "gofindpi: unknown command %q\n\n", cmd)
+		printUsage()
+		return 2
+	}
+
+	if err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "gofindpi: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printVersion prints build information and the provenance of the OUI database
+func printVersion() {
+	fmt.Printf("gofindpi %s (commit: %s, built: %s)\n", version, commit, date)
+
+	meta := data.Metadata
+	entries := data.EntryCount()
+	fmt.Printf("OUI database: %d entries\n", entries)
+	if meta.Generated == "" {
+		fmt.Println("  provenance not recorded (regenerate with make update-oui)")
+		return
+	}
+	fmt.Printf("  generated %s, %d conflicts resolved\n", meta.Generated, meta.Conflicts)
+	for _, src := range meta.Sources {
+		fmt.Printf("  %-16s %6d entries  sha256:%s  %s\n", src.Name, src.Entries, src.SHA256, src.Location)
+	}
+}
+
+// runScanCommand parses scan flags and runs a scan
+func runScanCommand(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gofindpi scan [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var (
+		opts    scanOptions
+		formats string
+		noRes   bool
+		noFP    bool
+		noMDNS  bool
+		noSSDP  bool
+		noInput bool
+		probes  string
+		names   string
+		ports   string
+		rules   string
+		leases  string
+		hist    string
+		noHist  bool
+		find    string
+	)
+	fs.StringVar(&opts.iface, "interface", "", "network interface to scan (e.g. eth0, en0)")
+	fs.StringVar(&opts.iface, "i", "", "shorthand for -interface")
+	fs.StringVar(&opts.targets, "target", "", "targets to scan: CIDR, range (10.0.0.10-80), address, comma list or @file")
+	fs.StringVar(&opts.targets, "t", "", "shorthand for -target")
+	fs.StringVar(&opts.exclude, "exclude", "", "addresses to skip, in the same syntax as -target")
+	fs.IntVar(&opts.maxHosts, "max-hosts", scanner.DefaultMaxHosts, "refuse to scan more than this many addresses (0 = no limit)")
+	fs.StringVar(&opts.family, "family", scanner.AddressFamilyIPv4, "address families to discover: ipv4, ipv6 (all-nodes multicast) or all")
+	fs.StringVar(&probes, "probe", scanner.ProbeICMP, "comma-separated liveness probes tried in order: icmp, tcp, arp (Linux, needs CAP_NET_RAW)")
+	fs.StringVar(&ports, "tcp-ports", defaultTCPPorts, "ports tried by the tcp probe; an open or refused port means the host is up")
+	fs.DurationVar(&opts.timeout, "timeout", 500*time.Millisecond, "per-host probe timeout")
+	fs.DurationVar(&opts.scanTimeout, "scan-timeout", 2*time.Minute, "overall scan deadline")
+	fs.IntVar(&opts.pingCount, "count", 1, "ICMP echo requests (or ARP requests) sent per host")
+	fs.IntVar(&opts.concurrency, "concurrency", 0, "maximum concurrent probes (0 = 32 per CPU core)")
+	fs.StringVar(&formats, "format", "text,json", "comma-separated output formats: text, json, none")
+	fs.StringVar(&opts.outputDir, "output-dir", "", "directory for output files (default: home directory)")
+	fs.StringVar(&find, "find", scanner.FamilyRaspberryPi, "comma-separated device families to list separately: "+familyNames()+" or all")
+	fs.StringVar(&rules, "rules", "", "category rules file, YAML or JSON (default: rules.yaml in the config directory, if present)")
+	fs.StringVar(&names, "resolvers", "dns,netbios,llmnr", "comma-separated name resolvers, queried in parallel; earlier ones win: dns, netbios, llmnr")
+	fs.StringVar(&opts.dnsServer, "dns-server", "", "DNS server for reverse lookups, e.g. your router (address or address:port; default: system resolver)")
+	fs.DurationVar(&opts.dnsTimeout, "dns-timeout", scanner.DefaultDNSTimeout, "time allowed for each reverse DNS lookup")
+	fs.DurationVar(&opts.resolveTime, "resolve-timeout", 15*time.Second, "time allowed for resolving hostnames after the probes finish")
+	fs.StringVar(&leases, "leases", "", "comma-separated DHCP lease files to take hostnames and client details from (dnsmasq, ISC dhcpd or Kea CSV)")
+	fs.BoolVar(&opts.offline, "known-offline", false, "also list devices with an active lease that did not answer (needs -leases)")
+	fs.BoolVar(&noRes, "no-resolve", false, "skip hostname resolution")
+	fs.BoolVar(&noMDNS, "no-mdns", false, "skip browsing for mDNS/DNS-SD names and services during the scan")
+	fs.BoolVar(&noSSDP, "no-ssdp", false, "skip the SSDP search for UPnP devices and their descriptions")
+	fs.BoolVar(&noFP, "no-fingerprint", false, "skip the mDNS and SSH banner queries that estimate Raspberry Pi model and OS")
+	fs.StringVar(&hist, "history", "", "scan history file every scan is appended to (default: "+history.DefaultFile+" in the config directory)")
+	fs.BoolVar(&noHist, "no-history", false, "do not record the scan in the history file")
+	fs.BoolVar(&noInput, "no-input", false, "never prompt; scan the first network if none is selected")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "gofindpi scan: unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+
+	if opts.timeout <= 0 || opts.scanTimeout <= 0 || opts.resolveTime <= 0 || opts.dnsTimeout <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+	if opts.pingCount < 1 {
+		return fmt.Errorf("-count must be at least 1")
+	}
+	if opts.concurrency < 0 {
+		return fmt.Errorf("-concurrency must not be negative")
+	}
+	switch opts.family {
+	case scanner.AddressFamilyIPv4, scanner.AddressFamilyIPv6, "all":
+	default:
+		return fmt.Errorf("unknown -family %q (want ipv4, ipv6 or all)", opts.family)
+	}
+	if opts.maxHosts < 0 {
+		return fmt.Errorf("-max-hosts must not be negative")
+	}
+
+	var err error
+	if opts.formats, err = parseFormats(formats); err != nil {
+		return err
+	}
+
+	if opts.probes, err = parseProbes(probes); err != nil {
+		return err
+	}
+	if opts.tcpPorts, err = parsePorts(ports); err != nil {
+		return fmt.Errorf("invalid -tcp-ports: %w", err)
+	}
+	if opts.resolvers, err = parseResolvers(names); err != nil {
+		return err
+	}
+	if opts.dnsServer, err = parseDNSServer(opts.dnsServer); err != nil {
+		return err
+	}
+
+	if opts.find, err = parseFind(find); err != nil {
+		return err
+	}
+
+	if opts.rules, err = loadRules(rules); err != nil {
+		return err
+	}
+	if opts.leases, err = loadLeases(leases); err != nil {
+		return err
+	}
+	if opts.offline && leases == "" {
+		return fmt.Errorf("-known-offline needs -leases")
+	}
+	if !noHist {
+		opts.history = historyFile(hist)
+	}
+
+	opts.resolve = !noRes
+	opts.fingerprint = !noFP
+	opts.mdns = !noMDNS
+	opts.ssdp = !noSSDP
+	opts.interactive = !noInput && opts.iface == "" && opts.targets == "" && stdinIsTerminal()
+
+	return runScan(opts)
+}
+
+// parseFormats validates the -format flag value
+func parseFormats(value string) ([]string, error) {
+	var formats []string
+	for _, f := range strings.Split(value, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch f {
+		case "":
+			continue
+		case "text", "json":
+			formats = append(formats, f)
+		case "none":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unknown output format %q (want text, json or none)", f)
+		}
+	}
+	return formats, nil
+}
+
+// defaultTCPPorts are ports commonly open (or actively refused) on home and office devices:
+// SSH, HTTP, HTTPS, SMB and Apple's iPhone sync service
+const defaultTCPPorts = "22,80,443,445,62078"
+
+// parseProbes validates the -probe flag value
+func parseProbes(value string) ([]string, error) {
+	var probes []string
+	seen := make(map[string]bool)
+	for _, p := range strings.Split(value, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch p {
+		case "":
+			continue
+		case scanner.ProbeICMP, scanner.ProbeARP, scanner.ProbeTCP:
+			if !seen[p] {
+				seen[p] = true
+				probes = append(probes, p)
+			}
+		default:
+			return nil, fmt.Errorf("unknown probe %q (want icmp, arp or tcp)", p)
+		}
+	}
+	if len(probes) == 0 {
+		return nil, fmt.Errorf("at least one probe method is required")
+	}
+	return probes, nil
+}
+
+// parseResolvers validates the -resolvers flag value
+func parseResolvers(value string) ([]string, error) {
+	var resolvers []string
+	seen := make(map[string]bool)
+	for _, r := range strings.Split(value, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		switch r {
+		case "":
+			continue
+		case scanner.ResolverDNS, scanner.ResolverNetBIOS, scanner.ResolverLLMNR:
+			if !seen[r] {
+				seen[r] = true
+				resolvers = append(resolvers, r)
+			}
+		default:
+			return nil, fmt.Errorf("unknown resolver %q (want dns, netbios or llmnr)", r)
+		}
+	}
+	if len(resolvers) == 0 {
+		return nil, fmt.Errorf("at least one resolver is required (use -no-resolve to skip resolution)")
+	}
+	return resolvers, nil
+}
+
+// parseDNSServer validates the -dns-server flag value and adds the default port
+func parseDNSServer(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		host, port = value, "53"
+	}
+	if _, err := netip.ParseAddr(host); err != nil {
+		return "", fmt.Errorf("invalid -dns-server %q: want an IP address, optionally with a port", value)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid -dns-server port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// parseFind parses the -find list into device families
+func parseFind(value string) ([]scanner.DeviceFamily, error) {
+	if strings.TrimSpace(value) == "all" {
+		return scanner.DeviceFamilies, nil
+	}
+	var families []scanner.DeviceFamily
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		family, ok := scanner.LookupDeviceFamily(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown device family %q (want %s or all)", name, familyNames())
+		}
+		families = append(families, family)
+	}
+	return families, nil
+}
+
+// familyNames lists the built-in device family names for help and error messages
+func familyNames() string {
+	names := make([]string, 0, len(scanner.DeviceFamilies))
+	for _, f := range scanner.DeviceFamilies {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// parsePorts validates a comma-separated list of TCP ports
+func parsePorts(value string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		port, err := strconv.Atoi(field)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		ports = append(ports, port)
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("at least one TCP port is required")
+	}
+	return ports, nil
+}
+
+// hasFormat reports whether the given output format was requested
+func (o scanOptions) hasFormat(format string) bool {
+	for _, f := range o.formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// stdinIsTerminal reports whether stdin is attached to an interactive terminal
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// rulesFile is the name of the default category rules file in the config directory
+const rulesFile = "rules.yaml"
+
+// loadRules reads the category rules named on the command line, or the default rules
+// file if one exists. It returns nil when there are no rules.
+func loadRules(path string) (*scanner.RuleSet, error) {
+	if path == "" {
+		dir, err := configDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(dir, rulesFile)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+	return scanner.LoadRules(path)
+}
+
+// loadLeases reads the lease files named in the -leases flag
+func loadLeases(value string) ([]scanner.Lease, error) {
+	var leases []scanner.Lease
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		fileLeases, err := scanner.ReadLeaseFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read leases: %w", err)
+		}
+		leases = append(leases, fileLeases...)
+	}
+	return leases, nil
+}
+
+// historyFile returns the history file to record scans in: the given path, or the
+// default in the config directory. It returns "" if there is no config directory.
+func historyFile(path string) string {
+	if path != "" {
+		return path
+	}
+	dir, err := configDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, history.DefaultFile)
+}
+
+// runHistoryCommand prints device timelines from the scan history
+func runHistoryCommand(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gofindpi history [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	file := fs.String("file", "", "history file to read (default: "+history.DefaultFile+" in the config directory)")
+	mac := fs.String("mac", "", "show the full timeline of the device with this MAC address")
+	ip := fs.String("ip", "", "only show devices that have used this IP address")
+	asJSON := fs.Bool("json", false, "print timelines as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	path := historyFile(*file)
+	if path == "" {
+		return fmt.Errorf("no history file; use -file")
+	}
+	scans, err := history.Open(path).Scans()
+	if err != nil {
+		return err
+	}
+	timelines, err := filterTimelines(history.Timelines(scans), *mac, *ip, len(scans))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(timelines)
+	}
+	if *mac != "" {
+		printTimeline(timelines[0])
+		return nil
+	}
+
+	if len(scans) == 0 {
+		fmt.Printf("No scans recorded in %s yet\n", path)
+		return nil
+	}
+	fmt.Printf("%d devices in %d scans (%s)\n\n", len(timelines), len(scans), path)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MAC ADDRESS\tLAST IP\tHOSTNAME\tMANUFACTURER\tFIRST SEEN\tLAST SEEN\tSCANS")
+	for _, t := range timelines {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			t.MAC, t.Sightings[len(t.Sightings)-1].IP, t.Hostname, t.Manufacturer,
+			formatHistoryTime(t.FirstSeen), formatHistoryTime(t.LastSeen), t.Scans)
+	}
+	return w.Flush()
+}
+
+// filterTimelines keeps the timeline of the device with the given MAC address and
+// those of devices that used the given IP address; empty filters keep everything.
+// Asking for a device that was never seen, or never seen at the address, is an error.
+func filterTimelines(timelines []history.Timeline, mac, ip string, scans int) ([]history.Timeline, error) {
+	if mac != "" {
+		normalized, err := data.NormalizeMAC(mac)
+		if err != nil {
+			return nil, err
+		}
+		t, ok := history.Find(timelines, normalized)
+		if !ok {
+			return nil, fmt.Errorf("%s has not been seen in %d recorded scans", normalized, scans)
+		}
+		timelines = []history.Timeline{t}
+	}
+	if ip != "" {
+		var matching []history.Timeline
+		for _, t := range timelines {
+			for _, a := range t.Addresses {
+				if a.IP == ip {
+					matching = append(matching, t)
+					break
+				}
+			}
+		}
+		if mac != "" && len(matching) == 0 {
+			return nil, fmt.Errorf("%s has not been seen at %s", timelines[0].MAC, ip)
+		}
+		timelines = matching
+	}
+	return timelines, nil
+}
+
+// printTimeline prints the addresses and sightings of one device
+func printTimeline(t history.Timeline) {
+	fmt.Printf("%s  %s\n", t.MAC, t.Manufacturer)
+	if t.Hostname != "" {
+		fmt.Printf("hostname:   %s\n", t.Hostname)
+	}
+	fmt.Printf("first seen: %s\n", formatHistoryTime(t.FirstSeen))
+	fmt.Printf("last seen:  %s (%d scans)\n", formatHistoryTime(t.LastSeen), t.Scans)
+
+	fmt.Println("\naddresses:")
+	for _, a := range t.Addresses {
+		fmt.Printf("  %-39s %s - %s\n", a.IP, formatHistoryTime(a.FirstSeen), formatHistoryTime(a.LastSeen))
+	}
+	fmt.Println("\nsightings:")
+	for _, s := range t.Sightings {
+		line := fmt.Sprintf("  %s  %-39s %s", formatHistoryTime(s.Time), s.IP, s.Hostname)
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// formatHistoryTime formats a timestamp from the history in local time
+func formatHistoryTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// runDiffCommand compares two scans saved as JSON, e.g. devicesfound.json
+func runDiffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gofindpi diff [flags] <old.json> <new.json>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the differences as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	before, err := readScanFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := readScanFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	d := history.Compare(before, after)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	}
+	fmt.Printf("%s (%s) -> %s (%s)\n", fs.Arg(0), before.Timestamp, fs.Arg(1), after.Timestamp)
+	if before.Network != after.Network {
+		fmt.Printf("note: the scans cover different networks (%s and %s)\n", before.Network, after.Network)
+	}
+	if d.Empty() {
+		fmt.Println("\nNo changes")
+		return nil
+	}
+	for _, dev := range d.New {
+		fmt.Printf("\n+ %s", describeDevice(dev))
+	}
+	for _, dev := range d.Missing {
+		fmt.Printf("\n- %s", describeDevice(dev))
+	}
+	for _, c := range d.Changed {
+		fmt.Printf("\n~ %s\n    %s", describeDevice(c.Device), describeChanges(c.Changes, "->"))
+	}
+	fmt.Printf("\n\n%d new, %d missing, %d changed\n", len(d.New), len(d.Missing), len(d.Changed))
+	return nil
+}
+
+// readScanFile reads a scan saved in the JSON output format
+func readScanFile(path string) (scanner.ScanResult, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return scanner.ScanResult{}, err
+	}
+	var result scanner.ScanResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return scanner.ScanResult{}, fmt.Errorf("%s is not a JSON scan: %w", path, err)
+	}
+	return result, nil
+}
+
+// describeDevice summarizes a device on one line for a diff
+func describeDevice(dev scanner.Device) string {
+	text := fmt.Sprintf("%s  %s  %s", dev.IP, dev.MAC, dev.Manufacturer)
+	if dev.Hostname != "" {
+		text += fmt.Sprintf(" (%s)", dev.Hostname)
+	}
+	return text
+}
+
+// describeChanges lists what changed about a device, e.g. "ip 10.0.0.5 -> 10.0.0.9"
+func describeChanges(changes []history.FieldChange, arrow string) string {
+	parts := make([]string, 0, len(changes))
+	for _, c := range changes {
+		before, after := c.Before, c.After
+		if before == "" {
+			before = "(none)"
+		}
+		if after == "" {
+			after = "(none)"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s %s", c.Field, before, arrow, after))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ouiOverrideFile is the name of the installed OUI database in the config directory
+const ouiOverrideFile = "oui.txt"
+
+// configDir returns gofindpi's configuration directory, e.g. ~/.config/gofindpi
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "gofindpi"), nil
+}
+
+// loadOUIOverride merges an installed OUI database over the embedded one. A missing
+// file is normal; a broken one is reported and ignored.
+func loadOUIOverride() {
+	dir, err := configDir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(dir, ouiOverrideFile)
+	file, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "gofindpi: ignoring OUI database: %v\n", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	entries, err := data.ParseOUIFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gofindpi: ignoring OUI database %s: %v\n", path, err)
+		return
+	}
+	data.Merge(entries)
+}
+
+// runOUICommand looks up manufacturer information for MAC addresses given as arguments
+func runOUICommand(args []string) error {
+	if len(args) > 0 && args[0] == "update" {
+		return runOUIUpdateCommand(args[1:])
+	}
+
+	fs := flag.NewFlagSet("oui", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gofindpi oui <mac> [mac...]\n       gofindpi oui update --from <file>\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	invalid := false
+	for _, mac := range fs.Args() {
+		result, found, err := data.Lookup(mac)
+		if err != nil {
+			invalid = true
+			fmt.Fprintf(os.Stderr, "gofindpi oui: %v\n", err)
+			continue
+		}
+		var line string
+		switch {
+		case found:
+			line = fmt.Sprintf("%s\t%s\t%s\t%s/%d", mac, result.Manufacturer, result.Category, result.Registry, result.PrefixBits)
+		case result.LocallyAdministered:
+			line = fmt.Sprintf("%s\tUnknown\t%s", mac, result.Category)
+		default:
+			line = fmt.Sprintf("%s\tUnknown", mac)
+		}
+		if result.Multicast {
+			line += "\t[multicast]"
+		}
+		if result.IsRaspberryPi {
+			line += "\t[Raspberry Pi]"
+		}
+		fmt.Println(line)
+	}
+	if invalid {
+		return errUsage
+	}
+	return nil
+}
+
+// runOUIUpdateCommand validates an IEEE CSV or Wireshark manuf file and installs it
+// in the config directory, where every later run merges it over the embedded database
+func runOUIUpdateCommand(args []string) error {
+	fs := flag.NewFlagSet("oui update", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gofindpi oui update --from <file>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	from := fs.String("from", "", "IEEE CSV (oui.csv, mam.csv, oui36.csv) or Wireshark manuf file to install")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	raw, err := os.ReadFile(*from)
+	if err != nil {
+		return err
+	}
+	entries, err := data.ParseOUIFile(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid OUI file: %w", *from, err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("%s contains no OUI entries", *from)
+	}
+
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	// Write to a temporary file first so a failed copy never leaves a truncated database
+	path := filepath.Join(dir, ouiOverrideFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to install %s: %w", path, err)
+	}
+
+	counts := make(map[int]int)
+	for _, e := range entries {
+		counts[e.Bits]++
+	}
+	fmt.Printf("Installed %d entries (MA-L %d, MA-M %d, MA-S %d) to %s\n",
+		len(entries), counts[24], counts[28], counts[36], path)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/james-see/gofindpi/history"
+	"github.com/james-see/gofindpi/scanner"
+)
+
+// historyScans are two scans in which the Pi moves from .20 to .21 and the laptop
+// stays at .30
+func historyScans() []scanner.ScanResult {
+	return []scanner.ScanResult{
+		{Timestamp: "2025-01-01T10:00:00Z", Network: "192.168.1.0/24", Devices: []scanner.Device{
+			{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", AddressFamily: scanner.AddressFamilyIPv4},
+			{IP: "192.168.1.30", MAC: "3c:22:fb:01:02:03", AddressFamily: scanner.AddressFamilyIPv4},
+		}},
+		{Timestamp: "2025-01-02T10:00:00Z", Network: "192.168.1.0/24", Devices: []scanner.Device{
+			{IP: "192.168.1.21", MAC: "b8:27:eb:12:34:56", AddressFamily: scanner.AddressFamilyIPv4},
+			{IP: "192.168.1.30", MAC: "3c:22:fb:01:02:03", AddressFamily: scanner.AddressFamilyIPv4},
+		}},
+	}
+}
+
+func TestFilterTimelines(t *testing.T) {
+	scans := historyScans()
+	timelines := history.Timelines(scans)
+
+	tests := []struct {
+		name    string
+		mac, ip string
+		want    []string // MACs of the timelines kept
+		err     string
+	}{
+		{name: "no filter", want: []string{"3c:22:fb:01:02:03", "b8:27:eb:12:34:56"}},
+		{name: "mac only", mac: "B8-27-EB-12-34-56", want: []string{"b8:27:eb:12:34:56"}},
+		{name: "unknown mac", mac: "dc:a6:32:ab:cd:ef", err: "dc:a6:32:ab:cd:ef has not been seen in 2 recorded scans"},
+		{name: "invalid mac", mac: "pi", err: "pi"},
+		{name: "ip only, earlier address", ip: "192.168.1.20", want: []string{"b8:27:eb:12:34:56"}},
+		{name: "ip only, unchanged address", ip: "192.168.1.30", want: []string{"3c:22:fb:01:02:03"}},
+		{name: "ip only, no match", ip: "192.168.1.99", want: nil},
+		{name: "mac and ip", mac: "b8:27:eb:12:34:56", ip: "192.168.1.21", want: []string{"b8:27:eb:12:34:56"}},
+		{name: "mac and another device's ip", mac: "b8:27:eb:12:34:56", ip: "192.168.1.30", err: "b8:27:eb:12:34:56 has not been seen at 192.168.1.30"},
+		{name: "mac and unused ip", mac: "b8:27:eb:12:34:56", ip: "192.168.1.99", err: "b8:27:eb:12:34:56 has not been seen at 192.168.1.99"},
+	}
+	for _, tt := range tests {
+		got, err := filterTimelines(timelines, tt.mac, tt.ip, len(scans))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var macs []string
+		for _, timeline := range got {
+			macs = append(macs, timeline.MAC)
+		}
+		if !reflect.DeepEqual(macs, tt.want) {
+			t.Errorf("%s: kept %v, want %v", tt.name, macs, tt.want)
+		}
+	}
+}
+
+func TestRunHistoryCommandFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := history.Open(path)
+	for _, scan := range historyScans() {
+		if err := store.Append(scan); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runHistoryCommand([]string{"-file", path, "-mac", "b8:27:eb:12:34:56", "-ip", "192.168.1.30"}); err == nil ||
+		!strings.Contains(err.Error(), "has not been seen at 192.168.1.30") {
+		t.Errorf("mac at another device's ip: %v", err)
+	}
+	if err := runHistoryCommand([]string{"-file", path, "-mac", "dc:a6:32:ab:cd:ef"}); err == nil ||
+		!strings.Contains(err.Error(), "in 2 recorded scans") {
+		t.Errorf("unknown mac: %v", err)
+	}
+	if err := runHistoryCommand([]string{"-file", path, "extra"}); err != errUsage {
+		t.Errorf("stray argument: %v", err)
+	}
+}
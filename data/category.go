@@ -0,0 +1,75 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// categoryRules map manufacturer names to categories. Rules are tried in order and
+// the first keyword found wins, so results never depend on map iteration order.
+// Keywords match whole words: "hp" matches "HP Inc." but not "Shanghai", and
+// multi-word keywords such as "palo alto" must appear as consecutive words.
+var categoryRules = []struct {
+	Category string
+	Keywords []string
+}{
+	{"Raspberry Pi", []string{"raspberry"}},
+	{"Network Equipment", []string{
+		"cisco", "juniper", "arista", "ubiquiti", "mikrotik", "netgear", "tp link",
+		"d link", "linksys", "belkin", "zyxel", "aruba", "fortinet", "palo alto",
+		"sonicwall", "meraki", "ruckus", "extreme", "brocade", "alcatel",
+	}},
+	{"Computer/Phone", []string{"apple"}},
+	{"Computer", []string{"dell", "hp", "hewlett", "lenovo", "intel", "microsoft", "acer"}},
+	{"Computer/Network", []string{"asus", "asustek"}},
+	{"Phone/TV", []string{"samsung", "sony"}},
+	{"Phone/IoT", []string{"xiaomi", "google"}},
+	{"Phone/Network", []string{"huawei"}},
+	{"Phone", []string{"oneplus", "oppo", "vivo", "motorola", "nokia"}},
+	{"IoT/Smart Home", []string{"amazon", "ring", "nest", "philips", "ecobee", "wyze", "tuya", "shelly"}},
+	{"IoT/Audio", []string{"sonos"}},
+	{"IoT/Embedded", []string{"espressif", "arduino"}},
+	{"TV/Display", []string{"lg"}},
+	{"TV", []string{"vizio", "tcl"}},
+	{"TV/Streaming", []string{"roku"}},
+	{"Gaming", []string{"nintendo", "playstation", "xbox", "valve"}},
+	{"Printer/Camera", []string{"canon"}},
+	{"Printer", []string{"epson", "brother", "xerox", "lexmark"}},
+	{"Security Camera", []string{"hikvision", "dahua", "axis", "lorex", "arlo"}},
+	{"Storage/NAS", []string{"synology", "qnap"}},
+	{"Storage", []string{"western digital", "seagate"}},
+	{"Virtual", []string{"vmware", "xensource", "parallels"}},
+}
+
+// tokenize lower-cases a name and splits it into words of letters and digits
+func tokenize(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsPhrase reports whether phrase occurs as consecutive words in tokens
+func containsPhrase(tokens, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		if slices.Equal(tokens[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// CategorizeManufacturer assigns a category to a manufacturer name from the keyword
+// rules, or "Unknown" if none match. The generator categorizes the embedded database
+// with it, and Merge categorizes the prefixes an OUI file adds.
+func CategorizeManufacturer(name string) string {
+	tokens := tokenize(name)
+	for _, rule := range categoryRules {
+		for _, keyword := range rule.Keywords {
+			if containsPhrase(tokens, tokenize(keyword)) {
+				return rule.Category
+			}
+		}
+	}
+	return "Unknown"
+}
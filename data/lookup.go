@@ -0,0 +1,148 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IEEE registries a prefix can be assigned from
+const (
+	RegistryMAL = "MA-L" // 24-bit OUI
+	RegistryMAM = "MA-M" // 28-bit block
+	RegistryMAS = "MA-S" // 36-bit block
+)
+
+// CategoryRandomized is reported for locally-administered addresses, which phones and
+// laptops generate for privacy and which therefore identify no manufacturer
+const CategoryRandomized = "Randomized MAC"
+
+// registries are searched longest prefix first, so a small block owned by one vendor
+// wins over the MA-L assignment it was carved from
+var registries = []struct {
+	name string
+	bits int
+}{
+	{RegistryMAS, 36},
+	{RegistryMAM, 28},
+	{RegistryMAL, 24},
+}
+
+// LookupResult describes the manufacturer assignment matching a MAC address
+type LookupResult struct {
+	MAC           string // Input normalized to lower-case colon notation
+	Prefix        string // Matched prefix, e.g. "b8:27:eb"
+	PrefixBits    int    // Length of the matched prefix in bits
+	Registry      string // IEEE registry the prefix comes from, e.g. "MA-L"
+	Manufacturer  string
+	Category      string
+	IsRaspberryPi bool
+
+	// Flag bits of the first octet. A locally-administered address was not assigned
+	// by the IEEE; it is usually a randomized (private) address or a virtual NIC.
+	LocallyAdministered bool
+	Multicast           bool
+}
+
+// Lookup finds the manufacturer of a MAC address or prefix. It accepts the common
+// notations in either case: colon (b8:27:eb:12:34:56), dash (B8-27-EB-12-34-56),
+// Cisco dotted (b827.eb12.3456), bare hex (b827eb123456) and unpadded BSD octets
+// (b8:27:eb:1:2:3). At least the first three octets must be present.
+//
+// The longest matching assignment wins: MA-S (36-bit), then MA-M (28-bit), then
+// MA-L (24-bit). Locally-administered addresses never match and are reported with
+// CategoryRandomized. The bool result reports whether any prefix matched. An error
+// is returned only when mac cannot be parsed.
+func Lookup(mac string) (LookupResult, bool, error) {
+	normalized, err := NormalizeMAC(mac)
+	if err != nil {
+		return LookupResult{}, false, err
+	}
+
+	result := LookupResult{
+		MAC:           normalized,
+		Manufacturer:  "Unknown",
+		Category:      "Unknown",
+		IsRaspberryPi: IsRaspberryPiOUI(normalized[:8]),
+	}
+
+	// The flag bits are the two low-order bits of the first octet
+	first, _ := strconv.ParseUint(normalized[:2], 16, 8)
+	result.Multicast = first&0x01 != 0
+	result.LocallyAdministered = first&0x02 != 0
+	if result.LocallyAdministered {
+		result.Category = CategoryRandomized
+		return result, false, nil
+	}
+
+	for _, reg := range registries {
+		prefix, ok := prefixKey(normalized, reg.bits)
+		if !ok {
+			continue
+		}
+		if info, ok := find(reg.bits, prefix); ok {
+			result.Prefix = prefix
+			result.PrefixBits = reg.bits
+			result.Registry = reg.name
+			result.Manufacturer = info.Name
+			result.Category = info.Category
+			return result, true, nil
+		}
+	}
+	return result, false, nil
+}
+
+// prefixKey truncates a normalized MAC to the database key for a prefix length:
+// "xx:xx:xx" for 24 bits, "xx:xx:xx:x" for 28 and "xx:xx:xx:xx:x" for 36. It fails
+// when the input is shorter than the prefix.
+func prefixKey(mac string, bits int) (string, bool) {
+	// Each hex digit takes one character, plus a colon after every second digit
+	n := bits/4 + (bits/4-1)/2
+	if len(mac) < n {
+		return "", false
+	}
+	return mac[:n], true
+}
+
+// NormalizeMAC converts a MAC address or prefix in any notation accepted by Lookup
+// into lower-case colon notation. Partial addresses keep their length, so "B827EB"
+// becomes "b8:27:eb".
+func NormalizeMAC(mac string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(mac))
+
+	var digits string
+	switch {
+	case strings.ContainsAny(s, ":-"):
+		groups := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '-' })
+		for _, g := range groups {
+			if len(g) == 1 {
+				g = "0" + g // BSD tools drop leading zeros
+			}
+			digits += g
+		}
+	case strings.Contains(s, "."):
+		for _, g := range strings.Split(s, ".") {
+			if len(g) > 4 {
+				return "", fmt.Errorf("invalid MAC address %q", mac)
+			}
+			digits += strings.Repeat("0", 4-len(g)) + g
+		}
+	default:
+		digits = s
+	}
+
+	if len(digits) < 6 || len(digits) > 12 || len(digits)%2 != 0 {
+		return "", fmt.Errorf("invalid MAC address %q", mac)
+	}
+	for _, r := range digits {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return "", fmt.Errorf("invalid MAC address %q", mac)
+		}
+	}
+
+	octets := make([]string, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		octets = append(octets, digits[i:i+2])
+	}
+	return strings.Join(octets, ":"), nil
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"b8:27:eb:12:34:56", "b8:27:eb:12:34:56"},
+		{"B8-27-EB-12-34-56", "b8:27:eb:12:34:56"},
+		{"b827.eb12.3456", "b8:27:eb:12:34:56"},
+		{"B827EB123456", "b8:27:eb:12:34:56"},
+		{"0:1b:2:3c:4:5", "00:1b:02:3c:04:05"},
+		{" b8:27:eb:12:34:56\n", "b8:27:eb:12:34:56"},
+		{"B827EB", "b8:27:eb"},
+		{"b8-27-eb-1", "b8:27:eb:01"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeMAC(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeMAC(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeMACInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"b8:27",
+		"b827e",
+		"zz:27:eb:12:34:56",
+		"b8:27:eb:12:34:56:78",
+		"b827.eb12.34567",
+		"b8:27:eb:12:34:5g",
+	} {
+		if got, err := NormalizeMAC(in); err == nil {
+			t.Errorf("NormalizeMAC(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		mac   string
+		found bool
+		want  LookupResult
+	}{
+		{
+			mac:   "B8-27-EB-12-34-56",
+			found: true,
+			want: LookupResult{
+				MAC: "b8:27:eb:12:34:56", Prefix: "b8:27:eb", PrefixBits: 24, Registry: RegistryMAL,
+				Manufacturer: "Raspberry Pi Foundation", Category: "Raspberry Pi", IsRaspberryPi: true,
+			},
+		},
+		{
+			mac:   "0000.0c12.3456",
+			found: true,
+			want: LookupResult{
+				MAC: "00:00:0c:12:34:56", Prefix: "00:00:0c", PrefixBits: 24, Registry: RegistryMAL,
+				Manufacturer: "Cisco Systems, Inc", Category: "Network Equipment",
+			},
+		},
+		{
+			mac:   "b827eb",
+			found: true,
+			want: LookupResult{
+				MAC: "b8:27:eb", Prefix: "b8:27:eb", PrefixBits: 24, Registry: RegistryMAL,
+				Manufacturer: "Raspberry Pi Foundation", Category: "Raspberry Pi", IsRaspberryPi: true,
+			},
+		},
+		{
+			mac:  "02:00:00:12:34:56",
+			want: LookupResult{MAC: "02:00:00:12:34:56", Manufacturer: "Unknown", Category: CategoryRandomized, LocallyAdministered: true},
+		},
+		{
+			mac:  "ba:27:eb:12:34:56", // b8:27:eb with the local bit set
+			want: LookupResult{MAC: "ba:27:eb:12:34:56", Manufacturer: "Unknown", Category: CategoryRandomized, LocallyAdministered: true},
+		},
+		{
+			mac:  "33:33:00:00:00:01",
+			want: LookupResult{MAC: "33:33:00:00:00:01", Manufacturer: "Unknown", Category: CategoryRandomized, LocallyAdministered: true, Multicast: true},
+		},
+	}
+	for _, tt := range tests {
+		got, found, err := Lookup(tt.mac)
+		if err != nil {
+			t.Errorf("Lookup(%q): %v", tt.mac, err)
+			continue
+		}
+		if found != tt.found || got != tt.want {
+			t.Errorf("Lookup(%q) = %+v, %v\nwant %+v, %v", tt.mac, got, found, tt.want, tt.found)
+		}
+	}
+
+	if _, _, err := Lookup("not a mac"); err == nil {
+		t.Error("Lookup of an invalid address did not fail")
+	}
+}
+
+func TestLookupMulticast(t *testing.T) {
+	got, _, err := Lookup("01:00:5e:00:00:fb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Multicast || got.LocallyAdministered {
+		t.Errorf("Lookup(01:00:5e:00:00:fb) = %+v, want multicast and globally administered", got)
+	}
+}
+
+func TestLookupLongestPrefix(t *testing.T) {
+	saved := overrides
+	overrides = make(map[int]map[string]ManufacturerInfo)
+	t.Cleanup(func() { overrides = saved })
+
+	Merge([]Entry{
+		{Prefix: "b8:27:eb:1", Bits: 28, Name: "Medium Block Ltd"},
+		{Prefix: "b8:27:eb:12:3", Bits: 36, Name: "Small Block Ltd"},
+	})
+
+	tests := []struct {
+		mac          string
+		prefix       string
+		bits         int
+		registry     string
+		manufacturer string
+	}{
+		{"b8:27:eb:12:34:56", "b8:27:eb:12:3", 36, RegistryMAS, "Small Block Ltd"},
+		{"b8:27:eb:1f:00:00", "b8:27:eb:1", 28, RegistryMAM, "Medium Block Ltd"},
+		{"b8:27:eb:22:00:00", "b8:27:eb", 24, RegistryMAL, "Raspberry Pi Foundation"},
+		{"b8:27:eb:12", "b8:27:eb:1", 28, RegistryMAM, "Medium Block Ltd"}, // Too short for MA-S
+	}
+	for _, tt := range tests {
+		got, found, err := Lookup(tt.mac)
+		if err != nil || !found {
+			t.Errorf("Lookup(%q) = %+v, %v, %v", tt.mac, got, found, err)
+			continue
+		}
+		if got.Prefix != tt.prefix || got.PrefixBits != tt.bits || got.Registry != tt.registry || got.Manufacturer != tt.manufacturer {
+			t.Errorf("Lookup(%q) matched %s/%d %s %q, want %s/%d %s %q", tt.mac,
+				got.Prefix, got.PrefixBits, got.Registry, got.Manufacturer,
+				tt.prefix, tt.bits, tt.registry, tt.manufacturer)
+		}
+	}
+}
+
+// TestLookupEmbeddedBlocks checks longest-prefix matching against the shipped
+// database rather than fixtures: every MA-M and MA-S block must win over its parent
+func TestLookupEmbeddedBlocks(t *testing.T) {
+	if Metadata.Generated == "" {
+		t.Skip("embedded database predates provenance tracking and has no MA-M or MA-S tables; regenerate it with make update-oui")
+	}
+
+	// Completes a prefix to a full address inside the block
+	suffixes := map[int]string{28: "0:00:01", 36: "0:01"}
+	for _, reg := range registries[:2] {
+		n := 0
+		embedded().each(reg.bits, func(prefix string, info ManufacturerInfo) {
+			n++
+			mac := prefix + suffixes[reg.bits]
+			result, ok, err := Lookup(mac)
+			if err != nil || !ok {
+				t.Errorf("Lookup(%q) = %v, %v", mac, ok, err)
+				return
+			}
+			if result.PrefixBits < reg.bits || !strings.HasPrefix(result.Prefix, prefix) {
+				t.Errorf("Lookup(%q) matched %s/%d, want the %s block %s (%s)", mac, result.Prefix, result.PrefixBits, reg.name, prefix, info.Name)
+			}
+		})
+		if n == 0 {
+			t.Errorf("embedded database has no %s entries", reg.name)
+		}
+	}
+
+	// 70:b3:d5 is carved up entirely into MA-S blocks
+	result, ok, err := Lookup("70:b3:d5:12:30:01")
+	if err != nil || !ok || result.Registry != RegistryMAS || result.Prefix != "70:b3:d5:12:3" || result.Manufacturer == "Unknown" {
+		t.Errorf("Lookup(70:b3:d5:12:30:01) = %+v, %v, %v; want its MA-S assignee", result, ok, err)
+	}
+}
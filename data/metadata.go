@@ -0,0 +1,22 @@
+package data
+
+// SourceInfo records one input the embedded database was generated from
+type SourceInfo struct {
+	Name     string // e.g. "IEEE MA-L"
+	Location string // URL or local path it was read from
+	SHA256   string // Hex digest of the raw input
+	Entries  int    // Entries parsed from it
+}
+
+// DatabaseMetadata describes how the embedded database was generated. It is filled
+// in by data/oui_meta.go, which scripts/generate_oui.go writes; the zero value means
+// the database predates provenance tracking.
+type DatabaseMetadata struct {
+	Generated string // RFC 3339 timestamp
+	Entries   int    // Unique prefixes across all registries
+	Conflicts int    // Prefixes assigned differently by two sources; the first source won
+	Sources   []SourceInfo
+}
+
+// Metadata is the provenance of the embedded database
+var Metadata DatabaseMetadata
@@ -0,0 +1,278 @@
+package data
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The embedded database (oui.bin) is a sorted binary table rather than a map literal,
+// so nothing is built at program start and names stay in the read-only blob until a
+// lookup needs one. Layout, with uvarint counts and lengths:
+//
+//	"OUI1"
+//	category count, then each category as length + bytes
+//	for MA-S, MA-M and MA-L: record count, then fixed-size records sorted by prefix
+//	name table: each name as length + bytes
+//
+// A record is the prefix as a big-endian integer (5, 4 or 3 bytes for 36, 28 and
+// 24 bits), the 3-byte offset of its name in the name table and a 1-byte category
+// index. Names are deduplicated, so vendors with hundreds of OUIs are stored once.
+const blobMagic = "OUI1"
+
+// nameOffsetSize bytes address the name table, limiting it to 16 MiB
+const nameOffsetSize = 3
+
+// table is one registry's sorted records within the blob
+type table struct {
+	records string // count * recordSize bytes
+	keySize int
+	count   int
+}
+
+func (t table) recordSize() int {
+	return t.keySize + nameOffsetSize + 1
+}
+
+// keySize is the bytes needed to hold a prefix of the given length
+func keySize(bits int) int {
+	return (bits + 7) / 8
+}
+
+// database is the decoded header of the blob. Records and names remain slices of
+// the embedded string.
+type database struct {
+	categories []string
+	tables     map[int]table // by prefix length
+	names      string
+}
+
+// decodeDatabase parses the blob header and locates each table; records are decoded
+// on demand
+func decodeDatabase(blob string) (*database, error) {
+	if !strings.HasPrefix(blob, blobMagic) {
+		return nil, errors.New("missing header")
+	}
+	r := blobReader{s: blob[len(blobMagic):]}
+
+	db := &database{tables: make(map[int]table)}
+	for n := r.uvarint(); n > 0 && r.err == nil; n-- {
+		db.categories = append(db.categories, r.str())
+	}
+	for _, reg := range registries {
+		t := table{keySize: keySize(reg.bits), count: int(r.uvarint())}
+		t.records = r.bytes(t.count * t.recordSize())
+		db.tables[reg.bits] = t
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	db.names = r.s
+	return db, nil
+}
+
+// lookup binary-searches the table for a prefix key such as "70:b3:d5:12:3"
+func (db *database) lookup(bits int, prefix string) (ManufacturerInfo, bool) {
+	t := db.tables[bits]
+	key, err := prefixValue(prefix)
+	if err != nil || t.count == 0 {
+		return ManufacturerInfo{}, false
+	}
+
+	size := t.recordSize()
+	lo, hi := 0, t.count
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		switch k := readUint(t.records[mid*size:], t.keySize); {
+		case k < key:
+			lo = mid + 1
+		case k > key:
+			hi = mid
+		default:
+			return db.record(t, mid), true
+		}
+	}
+	return ManufacturerInfo{}, false
+}
+
+// record decodes the i-th record of a table
+func (db *database) record(t table, i int) ManufacturerInfo {
+	rec := t.records[i*t.recordSize()+t.keySize:]
+	offset := readUint(rec, nameOffsetSize)
+	category := int(rec[nameOffsetSize])
+
+	var info ManufacturerInfo
+	r := blobReader{s: db.names[offset:]}
+	info.Name = r.str()
+	if category < len(db.categories) {
+		info.Category = db.categories[category]
+	}
+	return info
+}
+
+// each calls fn for every record of a table in prefix order
+func (db *database) each(bits int, fn func(prefix string, info ManufacturerInfo)) {
+	t := db.tables[bits]
+	for i := range t.count {
+		key := readUint(t.records[i*t.recordSize():], t.keySize)
+		fn(formatPrefix(key, bits), db.record(t, i))
+	}
+}
+
+// EncodeDatabase builds the binary database embedded as data/oui.bin from one map
+// per registry (RegistryMAL, RegistryMAM, RegistryMAS), keyed like Lookup's Prefix
+func EncodeDatabase(dbs map[string]map[string]ManufacturerInfo) ([]byte, error) {
+	var (
+		categories    []string
+		categoryIndex = make(map[string]int)
+		names         []byte
+		nameOffset    = make(map[string]int)
+		tables        []byte
+		prefixes      []string
+	)
+
+	for _, reg := range registries {
+		db := dbs[reg.name]
+		prefixes = prefixes[:0]
+		for prefix := range db {
+			prefixes = append(prefixes, prefix)
+		}
+		slices.Sort(prefixes) // Fixed-width hex keys sort like their values
+
+		tables = binary.AppendUvarint(tables, uint64(len(prefixes)))
+		for _, prefix := range prefixes {
+			info := db[prefix]
+			key, err := prefixValue(prefix)
+			if err != nil || formatPrefix(key, reg.bits) != prefix {
+				return nil, fmt.Errorf("%s: invalid prefix %q", reg.name, prefix)
+			}
+
+			category, ok := categoryIndex[info.Category]
+			if !ok {
+				category = len(categories)
+				if category > 0xff {
+					return nil, errors.New("too many categories")
+				}
+				categoryIndex[info.Category] = category
+				categories = append(categories, info.Category)
+			}
+
+			offset, ok := nameOffset[info.Name]
+			if !ok {
+				offset = len(names)
+				if offset >= 1<<(8*nameOffsetSize) {
+					return nil, errors.New("name table too large")
+				}
+				nameOffset[info.Name] = offset
+				names = binary.AppendUvarint(names, uint64(len(info.Name)))
+				names = append(names, info.Name...)
+			}
+
+			tables = appendUint(tables, key, keySize(reg.bits))
+			tables = appendUint(tables, uint64(offset), nameOffsetSize)
+			tables = append(tables, byte(category))
+		}
+	}
+
+	blob := []byte(blobMagic)
+	blob = binary.AppendUvarint(blob, uint64(len(categories)))
+	for _, c := range categories {
+		blob = binary.AppendUvarint(blob, uint64(len(c)))
+		blob = append(blob, c...)
+	}
+	blob = append(blob, tables...)
+	return append(blob, names...), nil
+}
+
+// prefixValue converts a database key to its integer value
+func prefixValue(prefix string) (uint64, error) {
+	var v uint64
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		switch {
+		case c == ':':
+			continue
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		default:
+			return 0, fmt.Errorf("invalid prefix %q", prefix)
+		}
+		v = v<<4 | uint64(c)
+	}
+	return v, nil
+}
+
+// formatPrefix is the inverse of prefixValue for a prefix of the given length
+func formatPrefix(v uint64, bits int) string {
+	const hexDigits = "0123456789abcdef"
+	nibbles := bits / 4
+	b := make([]byte, 0, nibbles+nibbles/2)
+	for i := range nibbles {
+		if i > 0 && i%2 == 0 {
+			b = append(b, ':')
+		}
+		b = append(b, hexDigits[v>>(4*(nibbles-1-i))&0xf])
+	}
+	return string(b)
+}
+
+// readUint decodes a big-endian integer of n bytes
+func readUint(s string, n int) uint64 {
+	var v uint64
+	for i := range n {
+		v = v<<8 | uint64(s[i])
+	}
+	return v
+}
+
+// appendUint appends v as a big-endian integer of n bytes
+func appendUint(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// blobReader reads uvarint-prefixed fields from the blob, remembering the first error
+type blobReader struct {
+	s   string
+	err error
+}
+
+func (r *blobReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	var v uint64
+	for i := 0; i < len(r.s) && i < binary.MaxVarintLen64; i++ {
+		b := r.s[i]
+		v |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			r.s = r.s[i+1:]
+			return v
+		}
+	}
+	r.err = errors.New("truncated database")
+	return 0
+}
+
+func (r *blobReader) bytes(n int) string {
+	if r.err != nil {
+		return ""
+	}
+	if n < 0 || n > len(r.s) {
+		r.err = errors.New("truncated database")
+		return ""
+	}
+	s := r.s[:n]
+	r.s = r.s[n:]
+	return s
+}
+
+func (r *blobReader) str() string {
+	return r.bytes(int(r.uvarint()))
+}
@@ -0,0 +1,240 @@
+package data
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestDatabaseRoundTrip(t *testing.T) {
+	dbs := map[string]map[string]ManufacturerInfo{
+		RegistryMAL: {
+			"b8:27:eb": {Name: "Raspberry Pi Foundation", Category: "Raspberry Pi"},
+			"00:00:0c": {Name: "Cisco Systems, Inc", Category: "Network Equipment"},
+			"00:00:0d": {Name: "Cisco Systems, Inc", Category: "Network Equipment"},
+		},
+		RegistryMAM: {
+			"70:b3:d5:1": {Name: "Example Devices, Inc.", Category: "Unknown"},
+		},
+		RegistryMAS: {
+			"70:b3:d5:12:3": {Name: "Example Sensors Ltd", Category: "Unknown"},
+			"00:1b:c5:00:a": {Name: "Raspberry Pi Foundation", Category: "Raspberry Pi"},
+		},
+	}
+
+	blob, err := EncodeDatabase(dbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := decodeDatabase(string(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, reg := range registries {
+		got := make(map[string]ManufacturerInfo)
+		db.each(reg.bits, func(prefix string, info ManufacturerInfo) {
+			got[prefix] = info
+		})
+		if !maps.Equal(got, dbs[reg.name]) {
+			t.Errorf("%s: decoded %v, want %v", reg.name, got, dbs[reg.name])
+		}
+		for prefix, want := range dbs[reg.name] {
+			if info, ok := db.lookup(reg.bits, prefix); !ok || info != want {
+				t.Errorf("%s: lookup(%q) = %v, %v; want %v", reg.name, prefix, info, ok, want)
+			}
+		}
+	}
+
+	if _, ok := db.lookup(24, "b8:27:ec"); ok {
+		t.Error("lookup of an absent prefix succeeded")
+	}
+	if _, ok := db.lookup(28, "70:b3:d5:2"); ok {
+		t.Error("lookup of an absent block succeeded")
+	}
+}
+
+func TestEncodeDatabaseDeterministic(t *testing.T) {
+	dbs := map[string]map[string]ManufacturerInfo{
+		RegistryMAL: {
+			"b8:27:eb": {Name: "Raspberry Pi Foundation", Category: "Raspberry Pi"},
+			"00:00:0c": {Name: "Cisco Systems, Inc", Category: "Network Equipment"},
+			"3c:22:fb": {Name: "Apple, Inc.", Category: "Computer/Phone"},
+		},
+	}
+	first, err := EncodeDatabase(dbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		again, err := EncodeDatabase(dbs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(first) {
+			t.Fatal("encoding the same database twice gave different blobs")
+		}
+	}
+}
+
+func TestEncodeDatabaseInvalidPrefix(t *testing.T) {
+	for _, prefix := range []string{"b8:27", "b8:27:eb:1", "B8:27:EB", "zz:27:eb"} {
+		dbs := map[string]map[string]ManufacturerInfo{RegistryMAL: {prefix: {Name: "x"}}}
+		if _, err := EncodeDatabase(dbs); err == nil {
+			t.Errorf("EncodeDatabase accepted MA-L prefix %q", prefix)
+		}
+	}
+}
+
+func TestDecodeDatabaseCorrupt(t *testing.T) {
+	blob, err := EncodeDatabase(map[string]map[string]ManufacturerInfo{
+		RegistryMAL: {"b8:27:eb": {Name: "Raspberry Pi Foundation", Category: "Raspberry Pi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := decodeDatabase("OUI0" + string(blob[4:])); err == nil {
+		t.Error("decoded a blob with the wrong header")
+	}
+	// Cut inside the MA-L records
+	if _, err := decodeDatabase(string(blob[:len(blob)-len("Raspberry Pi Foundation")-4])); err == nil {
+		t.Error("decoded a truncated blob")
+	}
+}
+
+// TestEmbeddedDatabase checks that the embedded blob decodes and that re-encoding its
+// contents reproduces it byte for byte, i.e. it is exactly what EncodeDatabase writes
+func TestEmbeddedDatabase(t *testing.T) {
+	db, err := decodeDatabase(ouiBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbs := make(map[string]map[string]ManufacturerInfo)
+	for _, reg := range registries {
+		m := make(map[string]ManufacturerInfo)
+		db.each(reg.bits, func(prefix string, info ManufacturerInfo) {
+			m[prefix] = info
+		})
+		dbs[reg.name] = m
+	}
+	if len(dbs[RegistryMAL]) == 0 {
+		t.Fatal("embedded database has no MA-L entries")
+	}
+
+	blob, err := EncodeDatabase(dbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(blob) != ouiBlob {
+		t.Errorf("re-encoded database differs from data/oui.bin (%d bytes, want %d)", len(blob), len(ouiBlob))
+	}
+}
+
+// legacyEntry is one element of the map literal the binary table replaced
+type legacyEntry struct {
+	prefix string
+	info   ManufacturerInfo
+}
+
+// legacyEntries lists every MA-L assignment keyed "xx:xx:xx", as data/oui.go
+// declared them
+func legacyEntries(db *database) []legacyEntry {
+	var entries []legacyEntry
+	db.each(24, func(prefix string, info ManufacturerInfo) {
+		entries = append(entries, legacyEntry{prefix, info})
+	})
+	return entries
+}
+
+// legacyMap builds the old map the way the runtime initializes a large map
+// literal: sized up front, then filled from static keys and values
+func legacyMap(entries []legacyEntry) map[string]ManufacturerInfo {
+	m := make(map[string]ManufacturerInfo, len(entries))
+	for _, e := range entries {
+		m[e.prefix] = e.info
+	}
+	return m
+}
+
+// BenchmarkInit compares what the data package does before its first lookup,
+// decoding the header of the embedded blob, with building the map literal it
+// replaced, which the runtime did at program start. B/op is the memory each takes
+// once initialized; embedded-bytes is the size of the blob in the binary.
+func BenchmarkInit(b *testing.B) {
+	b.Run("blob", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := decodeDatabase(ouiBlob); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(len(ouiBlob)), "embedded-bytes")
+	})
+	b.Run("map", func(b *testing.B) {
+		entries := legacyEntries(embedded())
+		b.ReportAllocs()
+		for b.Loop() {
+			legacyMap(entries)
+		}
+	})
+}
+
+// BenchmarkLookup compares a binary search of the MA-L table with an index into
+// the old map
+func BenchmarkLookup(b *testing.B) {
+	db := embedded()
+	var sample []string
+	db.each(24, func(prefix string, _ ManufacturerInfo) {
+		if len(sample) < 1024 {
+			sample = append(sample, prefix)
+		}
+	})
+	if len(sample) == 0 {
+		b.Skip("embedded database is empty")
+	}
+
+	b.Run("blob", func(b *testing.B) {
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			if _, ok := db.lookup(24, sample[i%len(sample)]); !ok {
+				b.Fatal("prefix not found")
+			}
+			i++
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		m := legacyMap(legacyEntries(db))
+		b.ReportAllocs()
+		i := 0
+		for b.Loop() {
+			if _, ok := m[sample[i%len(sample)]]; !ok {
+				b.Fatal("prefix not found")
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkLookupMAC measures the public Lookup, including parsing the address and
+// trying the MA-S and MA-M tables first
+func BenchmarkLookupMAC(b *testing.B) {
+	var sample []string
+	embedded().each(24, func(prefix string, _ ManufacturerInfo) {
+		if len(sample) < 1024 {
+			sample = append(sample, prefix+":12:34:56")
+		}
+	})
+	if len(sample) == 0 {
+		b.Skip("embedded database is empty")
+	}
+
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		if _, _, err := Lookup(sample[i%len(sample)]); err != nil {
+			b.Fatal(err)
+		}
+		i++
+	}
+}
@@ -0,0 +1,180 @@
+package data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Entry is a single manufacturer assignment read from an OUI file
+type Entry struct {
+	Prefix string // Database key, e.g. "b8:27:eb" or "70:b3:d5:12:3"
+	Bits   int    // 24 (MA-L), 28 (MA-M) or 36 (MA-S)
+	Name   string
+}
+
+// manufPattern matches a Wireshark manuf line: OUI<tab>Short Name<tab>Long Name, where
+// MA-M and MA-S blocks are written as a full address with a mask (70:B3:D5:12:30:00/36)
+var manufPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}(?:[:-][0-9A-Fa-f]{2}){2,5})(?:/(\d+))?\s+(\S+)\s*(.*)$`)
+
+// ParseOUIFile reads manufacturer assignments in either IEEE CSV format (oui.csv,
+// mam.csv, oui36.csv) or Wireshark manuf format, detected from the first line
+func ParseOUIFile(r io.Reader) ([]Entry, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(64)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.TrimPrefix(string(first), "\ufeff"), "Registry,") {
+		return ParseIEEECSV(br)
+	}
+	return ParseManuf(br)
+}
+
+// ParseIEEECSV reads an IEEE registry export:
+//
+//	Registry,Assignment,Organization Name,Organization Address
+//	MA-L,B827EB,Raspberry Pi Foundation,Mitchell Wood House Caldecote GB CB23 7NU
+//
+// The prefix length follows from the Assignment column: 6, 7 or 9 hex digits.
+func ParseIEEECSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			continue
+		}
+
+		assignment := strings.TrimSpace(record[1])
+		name := strings.TrimSpace(record[2])
+		bits := len(assignment) * 4
+		key, ok := blockKey(assignment, bits)
+		if !ok || name == "" {
+			line, _ := reader.FieldPos(1)
+			return nil, fmt.Errorf("line %d: invalid assignment %q", line, assignment)
+		}
+		entries = append(entries, Entry{Prefix: key, Bits: bits, Name: name})
+	}
+	return entries, nil
+}
+
+// ParseManuf reads the Wireshark manuf file:
+//
+//	00:00:0C	Cisco	Cisco Systems, Inc
+//	70:B3:D5:12:30:00/36	Amplitud	Amplitude Technologies
+//
+// Comments and blank lines are skipped, as are masks other than /24, /28 and /36.
+func ParseManuf(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		matches := manufPattern.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, fmt.Errorf("line %d: unrecognized entry %q", lineNum, line)
+		}
+
+		bits := 24
+		switch matches[2] {
+		case "":
+			if len(matches[1]) != len("xx:xx:xx") {
+				continue // Full addresses without a mask are not vendor blocks
+			}
+		case "24", "28", "36":
+			bits, _ = strconv.Atoi(matches[2])
+		default:
+			continue
+		}
+
+		name := strings.TrimSpace(matches[4])
+		if name == "" {
+			name = matches[3]
+		}
+		key, ok := blockKey(matches[1], bits)
+		if !ok {
+			return nil, fmt.Errorf("line %d: prefix %q is too short for /%d", lineNum, matches[1], bits)
+		}
+		entries = append(entries, Entry{Prefix: key, Bits: bits, Name: name})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Merge adds entries over the embedded database, replacing existing assignments.
+// Entries are categorized by CategorizeManufacturer, as the embedded ones are; a
+// replaced prefix whose new name matches no keyword keeps its old category. Merge
+// must be called before any lookups start, e.g. at program startup.
+func Merge(entries []Entry) {
+	for _, e := range entries {
+		if !knownBits(e.Bits) {
+			continue
+		}
+		category := CategorizeManufacturer(e.Name)
+		if old, ok := find(e.Bits, e.Prefix); ok && category == "Unknown" {
+			category = old.Category
+		}
+		if overrides[e.Bits] == nil {
+			overrides[e.Bits] = make(map[string]ManufacturerInfo)
+		}
+		overrides[e.Bits][e.Prefix] = ManufacturerInfo{Name: e.Name, Category: category}
+	}
+}
+
+// knownBits reports whether a registry holds prefixes of the given length
+func knownBits(bits int) bool {
+	for _, reg := range registries {
+		if reg.bits == bits {
+			return true
+		}
+	}
+	return false
+}
+
+// blockKey formats the first bits of a hex prefix in any separator style as a
+// database key. It fails for unsupported lengths and non-hex input.
+func blockKey(prefix string, bits int) (string, bool) {
+	digits := strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(prefix))
+	nibbles := bits / 4
+	if !knownBits(bits) || len(digits) < nibbles {
+		return "", false
+	}
+	digits = digits[:nibbles]
+	for _, r := range digits {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return "", false
+		}
+	}
+
+	var parts []string
+	for i := 0; i < len(digits); i += 2 {
+		parts = append(parts, digits[i:min(i+2, len(digits))])
+	}
+	return strings.Join(parts, ":"), true
+}
@@ -0,0 +1,117 @@
+package data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManuf(t *testing.T) {
+	input := `# Wireshark manuf
+00:00:0C	Cisco	Cisco Systems, Inc
+B8-27-EB	Raspberr
+70:B3:D5:12:30:00/36	Amplitud	Amplitude Technologies
+00:1B:C5:00:00:00/28	Converge	Converging Systems Inc.
+01:00:5E:00:00:00/25	IPv4mcast
+00:00:0C:07:AC:00	HSRP-VIP
+`
+	got, err := ParseOUIFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Prefix: "00:00:0c", Bits: 24, Name: "Cisco Systems, Inc"},
+		{Prefix: "b8:27:eb", Bits: 24, Name: "Raspberr"},
+		{Prefix: "70:b3:d5:12:3", Bits: 36, Name: "Amplitude Technologies"},
+		{Prefix: "00:1b:c5:0", Bits: 28, Name: "Converging Systems Inc."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseManufInvalid(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"00:00:0C\tCisco\n00:00:0C/28\tShort\tToo Short\n", "line 2"},
+		{"00:00:0C\tCisco\nnot an entry\n", "line 2"},
+	}
+	for _, tt := range tests {
+		_, err := ParseManuf(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseManuf(%q) error = %v, want one mentioning %q", tt.input, err, tt.err)
+		}
+	}
+}
+
+func TestParseIEEECSV(t *testing.T) {
+	input := "\ufeffRegistry,Assignment,Organization Name,Organization Address\n" +
+		"MA-L,B827EB,Raspberry Pi Foundation,Mitchell Wood House Caldecote GB CB23 7NU\n" +
+		"MA-M,70B3D51,\"Example Devices, Inc.\",Somewhere\n" +
+		"MA-S,70B3D5123,Example Sensors Ltd,Elsewhere\n"
+	got, err := ParseOUIFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Prefix: "b8:27:eb", Bits: 24, Name: "Raspberry Pi Foundation"},
+		{Prefix: "70:b3:d5:1", Bits: 28, Name: "Example Devices, Inc."},
+		{Prefix: "70:b3:d5:12:3", Bits: 36, Name: "Example Sensors Ltd"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := ParseIEEECSV(strings.NewReader("Registry,Assignment,Organization Name\nMA-L,B827,Short\n")); err == nil {
+		t.Error("a 16-bit assignment was accepted")
+	}
+}
+
+func TestMergeCategorizes(t *testing.T) {
+	saved := overrides
+	overrides = make(map[int]map[string]ManufacturerInfo)
+	t.Cleanup(func() { overrides = saved })
+
+	Merge([]Entry{
+		{Prefix: "70:b3:d5:12:3", Bits: 36, Name: "Hikvision Digital Technology"},
+		{Prefix: "70:b3:d5:45:6", Bits: 36, Name: "Obscure Widgets Ltd"},
+		{Prefix: "b8:27:eb", Bits: 24, Name: "RPi Foundation"}, // Matches no keyword
+	})
+
+	tests := []struct {
+		mac, name, category string
+	}{
+		{"70:b3:d5:12:34:56", "Hikvision Digital Technology", "Security Camera"},
+		{"70:b3:d5:45:67:89", "Obscure Widgets Ltd", "Unknown"},
+		{"b8:27:eb:12:34:56", "RPi Foundation", "Raspberry Pi"}, // Keeps the embedded category
+	}
+	for _, tt := range tests {
+		got, found, err := Lookup(tt.mac)
+		if err != nil || !found || got.Manufacturer != tt.name || got.Category != tt.category {
+			t.Errorf("Lookup(%q) = %q/%q (found %v, %v), want %q/%q",
+				tt.mac, got.Manufacturer, got.Category, found, err, tt.name, tt.category)
+		}
+	}
+}
+
+func TestCategorizeManufacturer(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Raspberry Pi Trading Ltd", "Raspberry Pi"},
+		{"HP Inc.", "Computer"},
+		{"Shanghai Example Co", "Unknown"},            // "hp" is not a word here
+		{"Corder Engineering Corporation", "Unknown"}, // Nor is "ring"
+		{"Ring LLC", "IoT/Smart Home"},
+		{"TP-LINK TECHNOLOGIES CO.,LTD.", "Network Equipment"},
+		{"Palo Alto Networks", "Network Equipment"},
+		{"Alto Palo Corp", "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := CategorizeManufacturer(tt.name); got != tt.want {
+			t.Errorf("CategorizeManufacturer(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,9 @@ toolchain go1.24.1
 require (
 	github.com/go-ping/ping v1.2.0
 	github.com/jaypipes/ghw v0.19.1
+	golang.org/x/net v0.44.0
+	golang.org/x/sys v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,9 +18,6 @@ require (
 	github.com/jaypipes/pcidb v1.1.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
-	golang.org/x/net v0.44.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	howett.net/plist v1.0.2-0.20250314012144-ee69052608d9 // indirect
 )
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"sort"
+
+	"github.com/james-see/gofindpi/scanner"
+)
+
+// Diff is what changed between two scans. Devices are matched by MAC address and
+// address family, so a device that moved to another IP is a change, not one device
+// missing and another new.
+type Diff struct {
+	From    string           `json:"from"` // Timestamp of the earlier scan
+	To      string           `json:"to"`
+	New     []scanner.Device `json:"new"`
+	Missing []scanner.Device `json:"missing"`
+	Changed []Change         `json:"changed"`
+}
+
+// Change is a device found by both scans whose details differ
+type Change struct {
+	Device  scanner.Device `json:"device"` // As found by the later scan
+	Changes []FieldChange  `json:"changes"`
+}
+
+// FieldChange is one detail of a device that changed
+type FieldChange struct {
+	Field  string `json:"field"` // "ip", "hostname", "manufacturer" or "category"
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Empty reports whether the scans found the same devices with the same details
+func (d Diff) Empty() bool {
+	return len(d.New) == 0 && len(d.Missing) == 0 && len(d.Changed) == 0
+}
+
+// Compare reports the devices after found that before did not, those it no longer
+// found, and those whose address, hostname, manufacturer or category changed. A
+// hostname that disappears is not a change, since that usually means a resolver did
+// not answer in time.
+func Compare(before, after scanner.ScanResult) Diff {
+	d := Diff{From: before.Timestamp, To: after.Timestamp}
+	earlier := byDevice(before.Devices)
+	later := byDevice(after.Devices)
+
+	for _, key := range sortedKeys(later) {
+		dev := later[key]
+		old, ok := earlier[key]
+		if !ok {
+			d.New = append(d.New, dev)
+			continue
+		}
+		var changes []FieldChange
+		for _, f := range []struct{ field, before, after string }{
+			{"ip", old.IP, dev.IP},
+			{"hostname", old.Hostname, dev.Hostname},
+			{"manufacturer", old.Manufacturer, dev.Manufacturer},
+			{"category", old.Category, dev.Category},
+		} {
+			if f.before != f.after && (f.field != "hostname" || f.after != "") {
+				changes = append(changes, FieldChange{f.field, f.before, f.after})
+			}
+		}
+		if len(changes) > 0 {
+			d.Changed = append(d.Changed, Change{Device: dev, Changes: changes})
+		}
+	}
+	for _, key := range sortedKeys(earlier) {
+		if _, ok := later[key]; !ok {
+			d.Missing = append(d.Missing, earlier[key])
+		}
+	}
+	return d
+}
+
+// byDevice indexes devices by MAC and address family. A MAC answering on several
+// addresses of one family, such as a router with aliases, is kept at the lowest.
+// Scans saved before IPv6 discovery have no address family and were all IPv4.
+func byDevice(devices []scanner.Device) map[string]scanner.Device {
+	index := make(map[string]scanner.Device, len(devices))
+	for _, dev := range devices {
+		family := dev.AddressFamily
+		if family == "" {
+			family = scanner.AddressFamilyIPv4
+		}
+		key := dev.MAC + "/" + family
+		if _, seen := index[key]; !seen {
+			index[key] = dev // Scan results are sorted by address
+		}
+	}
+	return index
+}
+
+func sortedKeys(devices map[string]scanner.Device) []string {
+	keys := make([]string, 0, len(devices))
+	for key := range devices {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
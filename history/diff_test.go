@@ -0,0 +1,155 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/james-see/gofindpi/scanner"
+)
+
+// readScan loads a saved scan from testdata
+func readScan(t *testing.T, name string) scanner.ScanResult {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result scanner.ScanResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// summary reduces a diff to the addresses of its devices and the fields that changed
+type summary struct {
+	New, Missing []string
+	Changed      map[string][]FieldChange
+}
+
+func summarize(d Diff) summary {
+	s := summary{Changed: make(map[string][]FieldChange)}
+	for _, dev := range d.New {
+		s.New = append(s.New, dev.IP)
+	}
+	for _, dev := range d.Missing {
+		s.Missing = append(s.Missing, dev.IP)
+	}
+	for _, c := range d.Changed {
+		s.Changed[c.Device.IP] = c.Changes
+	}
+	return s
+}
+
+func TestCompare(t *testing.T) {
+	pi := scanner.Device{IP: "10.0.0.20", MAC: "b8:27:eb:12:34:56", Manufacturer: "Raspberry Pi Foundation",
+		Category: "Raspberry Pi", Hostname: "pi.lan", AddressFamily: scanner.AddressFamilyIPv4}
+	router := scanner.Device{IP: "10.0.0.1", MAC: "00:0d:b9:41:6a:10", Manufacturer: "PC Engines GmbH",
+		Category: "Network Equipment", AddressFamily: scanner.AddressFamilyIPv4}
+	with := func(dev scanner.Device, change func(*scanner.Device)) scanner.Device {
+		change(&dev)
+		return dev
+	}
+
+	tests := []struct {
+		name          string
+		before, after []scanner.Device
+		want          summary
+	}{
+		{
+			name:   "unchanged",
+			before: []scanner.Device{router, pi},
+			after:  []scanner.Device{router, pi},
+		},
+		{
+			name:   "new and missing",
+			before: []scanner.Device{router},
+			after:  []scanner.Device{pi},
+			want:   summary{New: []string{"10.0.0.20"}, Missing: []string{"10.0.0.1"}},
+		},
+		{
+			name:   "moved to another address",
+			before: []scanner.Device{pi},
+			after:  []scanner.Device{with(pi, func(d *scanner.Device) { d.IP = "10.0.0.21" })},
+			want:   summary{Changed: map[string][]FieldChange{"10.0.0.21": {{"ip", "10.0.0.20", "10.0.0.21"}}}},
+		},
+		{
+			name:   "renamed and recategorized",
+			before: []scanner.Device{pi},
+			after: []scanner.Device{with(pi, func(d *scanner.Device) {
+				d.Hostname, d.Manufacturer, d.Category = "kitchen.lan", "Raspberry Pi Trading Ltd", "IoT"
+			})},
+			want: summary{Changed: map[string][]FieldChange{"10.0.0.20": {
+				{"hostname", "pi.lan", "kitchen.lan"},
+				{"manufacturer", "Raspberry Pi Foundation", "Raspberry Pi Trading Ltd"},
+				{"category", "Raspberry Pi", "IoT"},
+			}}},
+		},
+		{
+			// A resolver that did not answer in time is not a rename
+			name:   "hostname lost",
+			before: []scanner.Device{pi},
+			after:  []scanner.Device{with(pi, func(d *scanner.Device) { d.Hostname = "" })},
+		},
+		{
+			name:   "hostname learned",
+			before: []scanner.Device{with(pi, func(d *scanner.Device) { d.Hostname = "" })},
+			after:  []scanner.Device{pi},
+			want:   summary{Changed: map[string][]FieldChange{"10.0.0.20": {{"hostname", "", "pi.lan"}}}},
+		},
+		{
+			// Another family of the same MAC is another device
+			name:   "IPv6 address of a known device",
+			before: []scanner.Device{pi},
+			after: []scanner.Device{pi, with(pi, func(d *scanner.Device) {
+				d.IP, d.AddressFamily = "fe80::ba27:ebff:fe12:3456", scanner.AddressFamilyIPv6
+			})},
+			want: summary{New: []string{"fe80::ba27:ebff:fe12:3456"}},
+		},
+		{
+			name:   "aliases of one device",
+			before: []scanner.Device{router},
+			after:  []scanner.Device{router, with(router, func(d *scanner.Device) { d.IP = "10.0.0.254" })},
+		},
+		{
+			// Scans saved before IPv6 discovery have no address family
+			name:   "device without an address family",
+			before: []scanner.Device{with(pi, func(d *scanner.Device) { d.AddressFamily = "" })},
+			after:  []scanner.Device{pi},
+		},
+	}
+	for _, tt := range tests {
+		d := Compare(scanner.ScanResult{Timestamp: "before", Devices: tt.before}, scanner.ScanResult{Timestamp: "after", Devices: tt.after})
+		if d.From != "before" || d.To != "after" {
+			t.Errorf("%s: from %q to %q", tt.name, d.From, d.To)
+		}
+		if d.Empty() != (tt.want.New == nil && tt.want.Missing == nil && tt.want.Changed == nil) {
+			t.Errorf("%s: Empty() = %v", tt.name, d.Empty())
+		}
+		if tt.want.Changed == nil {
+			tt.want.Changed = map[string][]FieldChange{}
+		}
+		if got := summarize(d); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompareOldFormat(t *testing.T) {
+	// devicesfound.json from 2.0.0 has no address_family; its devices still match
+	// the IPv4 devices of a current scan
+	before := readScan(t, "devicesfound_v2.json")
+	after := readScan(t, "devicesfound.json")
+
+	want := summary{
+		New:     []string{"fe80::ba27:ebff:fe12:3456%eth0", "192.168.1.40"},
+		Missing: []string{"192.168.1.30"},
+		Changed: map[string][]FieldChange{"192.168.1.21": {{"ip", "192.168.1.20", "192.168.1.21"}}},
+	}
+	if got := summarize(Compare(before, after)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,240 @@
+// Package history keeps every scan in a JSON-lines file, one ScanResult per line, and
+// derives device timelines from it: when each MAC address was first and last seen,
+// and which addresses it used in between. Compare reports what changed between any
+// two scans.
+//
+//	store := history.Open(path)
+//	if err := store.Append(result); err != nil {
+//		return err
+//	}
+//	scans, err := store.Scans()
+//	if err != nil {
+//		return err
+//	}
+//	for _, t := range history.Timelines(scans) {
+//		fmt.Println(t.MAC, t.FirstSeen, t.LastSeen)
+//	}
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/james-see/gofindpi/scanner"
+)
+
+// DefaultFile is the name of the history file in gofindpi's config directory
+const DefaultFile = "history.jsonl"
+
+// Store is a scan history file. Scans are only ever appended, so the file can be
+// copied, trimmed or read with line-oriented tools.
+type Store struct {
+	path string
+}
+
+// Open returns the store kept in the file at path. The file is created by the first
+// Append.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the location of the history file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append records a scan at the end of the history
+func (s *Store) Append(result scanner.ScanResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed encoding scan: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(s.path), err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	// One write per scan, so a crash leaves at most a partial last line
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed writing %s: %w", s.path, err)
+	}
+	return f.Close()
+}
+
+// Scans reads every recorded scan, oldest first. A missing file is an empty history,
+// and a partial last line left by an interrupted write is ignored.
+func (s *Store) Scans() ([]scanner.ScanResult, error) {
+	var scans []scanner.ScanResult
+	err := s.each(func(result scanner.ScanResult) {
+		scans = append(scans, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scans, nil
+}
+
+// Latest returns the most recently recorded scan of network, or of any network if
+// network is empty. ok is false if there is none.
+func (s *Store) Latest(network string) (result scanner.ScanResult, ok bool, err error) {
+	err = s.each(func(scan scanner.ScanResult) {
+		if network == "" || scan.Network == network {
+			result, ok = scan, true
+		}
+	})
+	if err != nil {
+		return scanner.ScanResult{}, false, err
+	}
+	return result, ok, nil
+}
+
+// each passes every recorded scan to fn, oldest first, without holding the whole
+// history in memory
+func (s *Store) each(fn func(scanner.ScanResult)) error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		complete := err == nil
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var result scanner.ScanResult
+			if jsonErr := json.Unmarshal(line, &result); jsonErr == nil {
+				fn(result)
+			} else if complete {
+				return fmt.Errorf("%s:%d: %w", s.path, lineNumber, jsonErr)
+			}
+		}
+		if !complete {
+			return nil
+		}
+	}
+}
+
+// Timeline is the history of one device, identified by its MAC address
+type Timeline struct {
+	MAC          string     `json:"mac"`
+	Manufacturer string     `json:"manufacturer"`
+	Hostname     string     `json:"hostname,omitempty"` // Most recent name
+	FirstSeen    time.Time  `json:"first_seen"`
+	LastSeen     time.Time  `json:"last_seen"`
+	Scans        int        `json:"scans"` // Scans the device answered in
+	Addresses    []Address  `json:"addresses"`
+	Sightings    []Sighting `json:"sightings"`
+}
+
+// Address is an IP address a device used, and when it was seen using it
+type Address struct {
+	IP        string    `json:"ip"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Sighting is one scan a device answered in
+type Sighting struct {
+	Time     time.Time `json:"time"`
+	Network  string    `json:"network"`
+	IP       string    `json:"ip"`
+	Hostname string    `json:"hostname,omitempty"`
+}
+
+// Timelines builds the timeline of every device in the scans, most recently seen
+// first. Scans with an unreadable timestamp are skipped.
+func Timelines(scans []scanner.ScanResult) []Timeline {
+	byMAC := make(map[string]*Timeline)
+	for _, scan := range scans {
+		at, err := time.Parse(time.RFC3339, scan.Timestamp)
+		if err != nil {
+			continue
+		}
+		for _, dev := range scan.Devices {
+			if dev.MAC == "" {
+				continue
+			}
+			t := byMAC[dev.MAC]
+			if t == nil {
+				t = &Timeline{MAC: dev.MAC, FirstSeen: at, LastSeen: at}
+				byMAC[dev.MAC] = t
+			}
+			t.record(at, scan.Network, dev)
+		}
+	}
+
+	timelines := make([]Timeline, 0, len(byMAC))
+	for _, t := range byMAC {
+		sort.SliceStable(t.Sightings, func(i, j int) bool { return t.Sightings[i].Time.Before(t.Sightings[j].Time) })
+		sort.SliceStable(t.Addresses, func(i, j int) bool { return t.Addresses[i].FirstSeen.Before(t.Addresses[j].FirstSeen) })
+		timelines = append(timelines, *t)
+	}
+	sort.Slice(timelines, func(i, j int) bool {
+		if !timelines[i].LastSeen.Equal(timelines[j].LastSeen) {
+			return timelines[i].LastSeen.After(timelines[j].LastSeen)
+		}
+		return timelines[i].MAC < timelines[j].MAC
+	})
+	return timelines
+}
+
+// record adds a sighting of the device at time at. Scans are usually in order, but
+// a history merged from several machines may not be.
+func (t *Timeline) record(at time.Time, network string, dev scanner.Device) {
+	t.Scans++
+	t.Sightings = append(t.Sightings, Sighting{Time: at, Network: network, IP: dev.IP, Hostname: dev.Hostname})
+	if at.Before(t.FirstSeen) {
+		t.FirstSeen = at
+	}
+	if !at.Before(t.LastSeen) {
+		t.LastSeen = at
+		t.Manufacturer = dev.Manufacturer
+		if dev.Hostname != "" {
+			t.Hostname = dev.Hostname
+		}
+	}
+
+	for i := range t.Addresses {
+		a := &t.Addresses[i]
+		if a.IP != dev.IP {
+			continue
+		}
+		if at.Before(a.FirstSeen) {
+			a.FirstSeen = at
+		}
+		if at.After(a.LastSeen) {
+			a.LastSeen = at
+		}
+		return
+	}
+	t.Addresses = append(t.Addresses, Address{IP: dev.IP, FirstSeen: at, LastSeen: at})
+}
+
+// Find returns the timeline of the device with the given normalized MAC address
+func Find(timelines []Timeline, mac string) (Timeline, bool) {
+	for _, t := range timelines {
+		if t.MAC == mac {
+			return t, true
+		}
+	}
+	return Timeline{}, false
+}
@@ -0,0 +1,174 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/james-see/gofindpi/scanner"
+)
+
+func TestStore(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), "config", DefaultFile))
+
+	// A history that was never written is empty
+	if scans, err := store.Scans(); err != nil || len(scans) != 0 {
+		t.Fatalf("Scans() before the first Append = %v, %v", scans, err)
+	}
+	if _, ok, err := store.Latest(""); ok || err != nil {
+		t.Fatalf("Latest() before the first Append = %v, %v", ok, err)
+	}
+
+	recorded := []scanner.ScanResult{
+		{Timestamp: "2025-12-01T09:00:00Z", Network: "192.168.1.0/24", TotalDevices: 1,
+			Devices: []scanner.Device{{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", AddressFamily: scanner.AddressFamilyIPv4}}},
+		{Timestamp: "2025-12-01T10:00:00Z", Network: "10.0.0.0/24"},
+		{Timestamp: "2025-12-01T11:00:00Z", Network: "192.168.1.0/24"},
+	}
+	for _, scan := range recorded {
+		if err := store.Append(scan); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scans, err := store.Scans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scans, recorded) {
+		t.Errorf("Scans() =\n%+v\nwant\n%+v", scans, recorded)
+	}
+
+	tests := []struct {
+		network   string
+		timestamp string
+		ok        bool
+	}{
+		{"", "2025-12-01T11:00:00Z", true},
+		{"192.168.1.0/24", "2025-12-01T11:00:00Z", true},
+		{"10.0.0.0/24", "2025-12-01T10:00:00Z", true},
+		{"172.16.0.0/12", "", false},
+	}
+	for _, tt := range tests {
+		latest, ok, err := store.Latest(tt.network)
+		if err != nil || ok != tt.ok || latest.Timestamp != tt.timestamp {
+			t.Errorf("Latest(%q) = %q, %v, %v; want %q, %v", tt.network, latest.Timestamp, ok, err, tt.timestamp, tt.ok)
+		}
+	}
+}
+
+func TestStoreDamaged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	good := `{"timestamp":"2025-12-01T09:00:00Z","network":"192.168.1.0/24"}`
+
+	// An interrupted write leaves a partial last line, which is ignored
+	if err := os.WriteFile(path, []byte(good+"\n\n"+`{"timestamp":"2025-12-01T10:`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scans, err := Open(path).Scans()
+	if err != nil || len(scans) != 1 {
+		t.Errorf("partial last line: %d scans, %v", len(scans), err)
+	}
+
+	// A broken line in the middle is an error naming the line
+	if err := os.WriteFile(path, []byte(good+"\nnot json\n"+good+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path).Scans(); err == nil || !strings.Contains(err.Error(), DefaultFile+":2:") {
+		t.Errorf("broken line: %v", err)
+	}
+}
+
+func TestStoreOldFormat(t *testing.T) {
+	// A devicesfound.json saved by 2.0.0, copied into the history as one line
+	raw, err := os.ReadFile(filepath.Join("testdata", "devicesfound_v2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line bytes.Buffer
+	if err := json.Compact(&line, raw); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	if err := os.WriteFile(path, append(line.Bytes(), '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := Open(path)
+	if err := store.Append(readScan(t, "devicesfound.json")); err != nil {
+		t.Fatal(err)
+	}
+	scans, err := store.Scans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 2 || len(scans[0].Devices) != 3 || scans[0].Devices[1].AddressFamily != "" {
+		t.Fatalf("scans %+v", scans)
+	}
+
+	d := Compare(scans[0], scans[1])
+	if len(d.Changed) != 1 || d.Changed[0].Device.IP != "192.168.1.21" || len(d.Missing) != 1 || len(d.New) != 2 {
+		t.Errorf("diff against the old scan %+v", summarize(d))
+	}
+}
+
+func TestTimelines(t *testing.T) {
+	pi := func(ip, hostname string) scanner.Device {
+		return scanner.Device{IP: ip, MAC: "b8:27:eb:12:34:56", Manufacturer: "Raspberry Pi Foundation", Hostname: hostname}
+	}
+	laptop := scanner.Device{IP: "192.168.1.30", MAC: "3c:22:fb:01:02:03", Manufacturer: "Apple, Inc."}
+	router := scanner.Device{IP: "192.168.1.1", MAC: "00:0d:b9:41:6a:10", Manufacturer: "PC Engines GmbH"}
+
+	// Merged from two machines, so not in time order
+	scans := []scanner.ScanResult{
+		{Timestamp: "2025-12-02T09:00:00Z", Network: "lan", Devices: []scanner.Device{router, pi("192.168.1.21", "")}},
+		{Timestamp: "2025-12-01T09:00:00Z", Network: "lan", Devices: []scanner.Device{router, pi("192.168.1.20", "raspberrypi"), laptop}},
+		{Timestamp: "2025-12-03T09:00:00Z", Network: "lan", Devices: []scanner.Device{pi("192.168.1.20", "kitchen"), {IP: "192.168.1.50"}}},
+		{Timestamp: "yesterday", Network: "lan", Devices: []scanner.Device{laptop}},
+	}
+	at := func(day int) time.Time { return time.Date(2025, 12, day, 9, 0, 0, 0, time.UTC) }
+
+	timelines := Timelines(scans)
+	var macs []string
+	for _, tl := range timelines {
+		macs = append(macs, tl.MAC)
+	}
+	// Most recently seen first, ties by MAC; the device without a MAC is left out
+	if want := []string{"b8:27:eb:12:34:56", "00:0d:b9:41:6a:10", "3c:22:fb:01:02:03"}; !reflect.DeepEqual(macs, want) {
+		t.Fatalf("timelines %v, want %v", macs, want)
+	}
+
+	want := Timeline{
+		MAC:          "b8:27:eb:12:34:56",
+		Manufacturer: "Raspberry Pi Foundation",
+		Hostname:     "kitchen",
+		FirstSeen:    at(1),
+		LastSeen:     at(3),
+		Scans:        3,
+		Addresses: []Address{
+			{IP: "192.168.1.20", FirstSeen: at(1), LastSeen: at(3)},
+			{IP: "192.168.1.21", FirstSeen: at(2), LastSeen: at(2)},
+		},
+		Sightings: []Sighting{
+			{Time: at(1), Network: "lan", IP: "192.168.1.20", Hostname: "raspberrypi"},
+			{Time: at(2), Network: "lan", IP: "192.168.1.21"},
+			{Time: at(3), Network: "lan", IP: "192.168.1.20", Hostname: "kitchen"},
+		},
+	}
+	if got, ok := Find(timelines, "b8:27:eb:12:34:56"); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("Pi timeline:\n got %+v\nwant %+v", got, want)
+	}
+
+	// The scan with the unreadable timestamp does not count
+	if laptop, _ := Find(timelines, "3c:22:fb:01:02:03"); laptop.Scans != 1 || !laptop.LastSeen.Equal(at(1)) {
+		t.Errorf("laptop timeline %+v", laptop)
+	}
+	if _, ok := Find(timelines, "dc:a6:32:ab:cd:ef"); ok {
+		t.Error("found a device that was never seen")
+	}
+}
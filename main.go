@@ -6,18 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/go-ping/ping"
 	"github.com/james-see/gofindpi/data"
+	"github.com/james-see/gofindpi/history"
+	"github.com/james-see/gofindpi/scanner"
 	"github.com/jaypipes/ghw"
 )
 
@@ -81,35 +81,6 @@ const (
 	piSymbol     = "🍓"
 )
 
-// Device represents a discovered network device with full identification
-type Device struct {
-	IP            string `json:"ip"`
-	MAC           string `json:"mac"`
-	Manufacturer  string `json:"manufacturer"`
-	Category      string `json:"category"`
-	IsRaspberryPi bool   `json:"is_raspberry_pi"`
-	Hostname      string `json:"hostname,omitempty"`
-}
-
-// ScanResult contains the complete scan results with metadata
-type ScanResult struct {
-	Timestamp    string         `json:"timestamp"`
-	Network      string         `json:"network"`
-	Duration     float64        `json:"duration_seconds"`
-	TotalDevices int            `json:"total_devices"`
-	PiCount      int            `json:"raspberry_pi_count"`
-	Devices      []Device       `json:"devices"`
-	Statistics   map[string]int `json:"manufacturer_statistics"`
-	Categories   map[string]int `json:"category_statistics"`
-}
-
-// Configuration for scanning
-type scanConfig struct {
-	timeout       time.Duration
-	maxGoroutines int
-	pingCount     int
-}
-
 // printHeader displays the application header
 func printHeader() {
 	width := 62
@@ -135,7 +106,8 @@ func centerText(text string, width int) string {
 
 // printSection prints a section header
 func printSection(title string) {
-	fmt.Printf("\n%s%s %s %s%s\n", colorBold+colorYellow, lineHorizontal+lineHorizontal, title, strings.Repeat(lineHorizontal, 50-len(title)), colorReset)
+	fill := max(50-len(title), 2)
+	fmt.Printf("\n%s%s %s %s%s\n", colorBold+colorYellow, lineHorizontal+lineHorizontal, title, strings.Repeat(lineHorizontal, fill), colorReset)
 }
 
 // printProgressBar displays a progress bar
@@ -151,61 +123,24 @@ func printProgressBar(current, total int, width int) {
 		current, total)
 }
 
-// lookupManufacturer retrieves manufacturer info from the OUI database
-func lookupManufacturer(mac string) (data.ManufacturerInfo, bool) {
-	if len(mac) < 8 {
-		return data.ManufacturerInfo{Name: "Unknown", Category: "Unknown"}, false
-	}
-
-	// Normalize MAC address prefix
-	macPrefix := strings.ToLower(mac[:8])
-
-	// Ensure format is xx:xx:xx
-	if !strings.Contains(macPrefix, ":") {
-		// Convert formats like xxxx.xxxx to xx:xx:xx
-		macPrefix = strings.ReplaceAll(macPrefix, ".", "")
-		macPrefix = strings.ReplaceAll(macPrefix, "-", "")
-		if len(macPrefix) >= 6 {
-			macPrefix = fmt.Sprintf("%s:%s:%s", macPrefix[0:2], macPrefix[2:4], macPrefix[4:6])
+// outputDir returns the directory output files are written to, defaulting to the user's home
+func outputDir(dir string) (string, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("failed to create output directory %s: %w", dir, err)
 		}
+		return dir, nil
 	}
-
-	// Lookup in database
-	if info, ok := data.OUIDatabase[macPrefix]; ok {
-		return info, true
-	}
-
-	return data.ManufacturerInfo{Name: "Unknown", Category: "Unknown"}, false
-}
-
-// isRaspberryPi checks if the device is a Raspberry Pi based on MAC prefix
-func isRaspberryPi(mac string) bool {
-	if len(mac) < 8 {
-		return false
-	}
-	macPrefix := strings.ToLower(mac[:8])
-	return data.IsRaspberryPiOUI(macPrefix)
-}
-
-// resolveHostname attempts to get the hostname for an IP address
-func resolveHostname(ip string) string {
-	names, err := net.LookupAddr(ip)
-	if err != nil || len(names) == 0 {
-		return ""
-	}
-	// Remove trailing dot if present
-	hostname := strings.TrimSuffix(names[0], ".")
-	return hostname
-}
-
-// Writes device list to a text file in user's home directory
-func writeToFile(devices []Device, fileName string) error {
 	dirname, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
+	return dirname, nil
+}
 
-	filePath := fmt.Sprintf("%s/%s", dirname, fileName)
+// Writes device list to a text file in the output directory
+func writeToFile(devices []scanner.Device, dir, fileName string) error {
+	filePath := filepath.Join(dir, fileName)
 	file, err := os.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("failed creating file %s: %w", filePath, err)
@@ -221,8 +156,23 @@ func writeToFile(devices []Device, fileName string) error {
 		if dev.Hostname != "" {
 			line += fmt.Sprintf(" hostname:%s", dev.Hostname)
 		}
+		if dev.Workgroup != "" {
+			line += fmt.Sprintf(" workgroup:%s", dev.Workgroup)
+		}
+		if dev.OS != "" {
+			line += fmt.Sprintf(" os:%q", dev.OS)
+		}
+		if dev.UPnP != nil && dev.UPnP.FriendlyName != "" {
+			line += fmt.Sprintf(" upnp:%q", dev.UPnP.FriendlyName)
+		}
+		if dev.DeviceFamily != "" {
+			line += fmt.Sprintf(" family:%s", dev.DeviceFamily)
+		}
 		if dev.IsRaspberryPi {
 			line += " [Raspberry Pi]"
+			if dev.PiModel != "" {
+				line += fmt.Sprintf(" model:%q", dev.PiModel)
+			}
 		}
 		_, err := writer.WriteString(line + "\n")
 		if err != nil {
@@ -233,14 +183,9 @@ func writeToFile(devices []Device, fileName string) error {
 	return nil
 }
 
-// writeJSON writes the scan results as JSON
-func writeJSON(result ScanResult, fileName string) error {
-	dirname, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	filePath := fmt.Sprintf("%s/%s", dirname, fileName)
+// writeJSON writes the scan results as JSON to the output directory
+func writeJSON(result scanner.ScanResult, dir, fileName string) error {
+	filePath := filepath.Join(dir, fileName)
 	file, err := os.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("failed creating file %s: %w", filePath, err)
@@ -257,155 +202,6 @@ func writeJSON(result ScanResult, fileName string) error {
 	return nil
 }
 
-// Pings an IP address with proper context and timeout
-func pingIP(ctx context.Context, ipAddress string, config scanConfig) bool {
-	pinger, err := ping.NewPinger(ipAddress)
-	if err != nil {
-		return false
-	}
-	defer pinger.Stop()
-
-	pinger.Count = config.pingCount
-	pinger.Timeout = config.timeout
-	pinger.SetPrivileged(false) // Use unprivileged ICMP
-
-	received := false
-	pinger.OnRecv = func(pkt *ping.Packet) {
-		received = true
-	}
-
-	// Run with context
-	done := make(chan bool, 1)
-	go func() {
-		err = pinger.Run()
-		done <- true
-	}()
-
-	select {
-	case <-ctx.Done():
-		pinger.Stop()
-		return false
-	case <-done:
-		return received && err == nil
-	}
-}
-
-// Scans a range of IPs concurrently with proper goroutine management
-func scanIPRange(ctx context.Context, ips []string, config scanConfig) []string {
-	var (
-		wg        sync.WaitGroup
-		mu        sync.Mutex
-		foundIPs  []string
-		semaphore = make(chan struct{}, config.maxGoroutines)
-		completed = 0
-		total     = len(ips)
-	)
-
-	fmt.Printf("  %sScanning %d addresses...%s\n\n", colorDim, total, colorReset)
-
-	for _, ip := range ips {
-		wg.Add(1)
-		semaphore <- struct{}{} // Acquire semaphore
-
-		go func(ipAddr string) {
-			defer wg.Done()
-			defer func() { <-semaphore }() // Release semaphore
-
-			if pingIP(ctx, ipAddr, config) {
-				mu.Lock()
-				foundIPs = append(foundIPs, ipAddr)
-				mu.Unlock()
-			}
-
-			mu.Lock()
-			completed++
-			printProgressBar(completed, total, 40)
-			mu.Unlock()
-		}(ip)
-	}
-
-	wg.Wait()
-	close(semaphore)
-	fmt.Println() // New line after progress bar
-	return foundIPs
-}
-
-// Parses ARP table to get MAC addresses and identifies devices
-func parseARPTable(foundIPs []string, resolveHosts bool) []Device {
-	out, err := exec.Command("arp", "-a").Output()
-	if err != nil {
-		log.Printf("Error running arp command: %v", err)
-		return nil
-	}
-
-	var devices []Device
-	foundIPMap := make(map[string]bool)
-	for _, ip := range foundIPs {
-		foundIPMap[ip] = true
-	}
-
-	lines := strings.Split(string(out), "\n")
-	for _, line := range lines {
-		if !strings.Contains(line, "(") || strings.Contains(line, "incomplete") {
-			continue
-		}
-
-		// Parse: ? (192.168.1.1) at aa:bb:cc:dd:ee:ff on en0 ifscope [ethernet]
-		parts := strings.Split(line, "(")
-		if len(parts) < 2 {
-			continue
-		}
-
-		ipPart := strings.Split(parts[1], ")")
-		if len(ipPart) < 2 {
-			continue
-		}
-		ip := strings.TrimSpace(ipPart[0])
-
-		macParts := strings.Split(ipPart[1], "at")
-		if len(macParts) < 2 {
-			continue
-		}
-
-		macPart := strings.Split(macParts[1], "on")
-		if len(macPart) < 1 {
-			continue
-		}
-		mac := strings.TrimSpace(macPart[0])
-
-		// Only include devices we actually pinged successfully
-		if !foundIPMap[ip] {
-			continue
-		}
-
-		// Lookup manufacturer info
-		info, _ := lookupManufacturer(mac)
-
-		// Check if Raspberry Pi
-		isPi := isRaspberryPi(mac)
-		if isPi {
-			info.Category = "Raspberry Pi"
-		}
-
-		dev := Device{
-			IP:            ip,
-			MAC:           mac,
-			Manufacturer:  info.Name,
-			Category:      info.Category,
-			IsRaspberryPi: isPi,
-		}
-
-		// Optionally resolve hostname
-		if resolveHosts {
-			dev.Hostname = resolveHostname(ip)
-		}
-
-		devices = append(devices, dev)
-	}
-
-	return devices
-}
-
 // Gets the number of CPU cores
 func getCPUCores() int {
 	if runtime.GOOS == "darwin" {
@@ -425,74 +221,19 @@ func getCPUCores() int {
 	return runtime.NumCPU()
 }
 
-// Generates all IPs in a /24 subnet
-func generateIPRange(baseIP string) []string {
-	parts := strings.Split(baseIP, ".")
-	if len(parts) != 4 {
-		return nil
-	}
-
-	basePrefix := strings.Join(parts[:3], ".")
-	ips := make([]string, 0, 254)
-
-	for i := 1; i <= 254; i++ {
-		ips = append(ips, fmt.Sprintf("%s.%d", basePrefix, i))
-	}
-
-	return ips
-}
-
-// Gets all local IP addresses
-func getLocalIPs() []string {
-	var localIPs []string
-
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		log.Printf("Error getting network interfaces: %v", err)
-		return nil
-	}
-
-	for _, address := range addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				localIPs = append(localIPs, ipnet.IP.String())
-			}
-		}
-	}
-
-	return localIPs
-}
-
-// Converts IP to CIDR notation
-func ipToCIDR(ip string) string {
-	parts := strings.Split(ip, ".")
-	if len(parts) != 4 {
-		return ip
-	}
-	return fmt.Sprintf("%s.%s.%s.0/24", parts[0], parts[1], parts[2])
-}
-
-// calculateStatistics generates manufacturer and category statistics
-func calculateStatistics(devices []Device) (map[string]int, map[string]int) {
-	manufacturerStats := make(map[string]int)
-	categoryStats := make(map[string]int)
-
-	for _, dev := range devices {
-		manufacturerStats[dev.Manufacturer]++
-		categoryStats[dev.Category]++
-	}
-
-	return manufacturerStats, categoryStats
-}
-
 // printDeviceTable prints devices in a nice table format
-func printDeviceTable(devices []Device) {
+func printDeviceTable(devices []scanner.Device) {
 	if len(devices) == 0 {
 		return
 	}
 
-	fmt.Printf("\n  %s%-16s %-18s %-30s %s%s\n",
-		colorBold, "IP ADDRESS", "MAC ADDRESS", "MANUFACTURER", "CATEGORY", colorReset)
+	ipWidth := 16
+	for _, dev := range devices {
+		ipWidth = max(ipWidth, len(dev.IP)+1)
+	}
+
+	fmt.Printf("\n  %s%-*s %-18s %-30s %s%s\n",
+		colorBold, ipWidth, "IP ADDRESS", "MAC ADDRESS", "MANUFACTURER", "CATEGORY", colorReset)
 	fmt.Printf("  %s%s%s\n", colorDim, strings.Repeat(lineHorizontal, 80), colorReset)
 
 	for _, dev := range devices {
@@ -511,6 +252,8 @@ func printDeviceTable(devices []Device) {
 			categoryColor = colorYellow
 		case "IoT/Smart Home", "IoT/Audio", "IoT/Embedded":
 			categoryColor = colorMagenta
+		case data.CategoryRandomized:
+			categoryColor = colorBlue
 		case "Unknown":
 			categoryColor = colorDim
 		}
@@ -519,14 +262,18 @@ func printDeviceTable(devices []Device) {
 		if dev.IsRaspberryPi {
 			piIndicator = " " + piSymbol
 		}
+		friendlyName := ""
+		if dev.UPnP != nil && dev.UPnP.FriendlyName != "" {
+			friendlyName = fmt.Sprintf(" %s%s%s", colorDim, dev.UPnP.FriendlyName, colorReset)
+		}
 
-		fmt.Printf("  %-16s %-18s %-30s %s%-15s%s%s\n",
-			dev.IP, dev.MAC, manufacturer, categoryColor, dev.Category, colorReset, piIndicator)
+		fmt.Printf("  %-*s %-18s %-30s %s%-15s%s%s%s\n",
+			ipWidth, dev.IP, dev.MAC, manufacturer, categoryColor, dev.Category, colorReset, piIndicator, friendlyName)
 	}
 }
 
 // printStatistics displays a summary of the scan results
-func printStatistics(devices []Device, piCount int, manufacturerStats map[string]int, categoryStats map[string]int) {
+func printStatistics(devices []scanner.Device, piCount int, manufacturerStats map[string]int, categoryStats map[string]int) {
 	printSection("SCAN RESULTS")
 
 	// Summary in a cleaner format
@@ -624,6 +371,9 @@ func printStatistics(devices []Device, piCount int, manufacturerStats map[string
 		case "Gaming":
 			icon = "🎮"
 			color = colorGreen
+		case data.CategoryRandomized:
+			icon = "🎭"
+			color = colorBlue
 		case "Unknown":
 			icon = "❓"
 			color = colorDim
@@ -635,129 +385,253 @@ func printStatistics(devices []Device, piCount int, manufacturerStats map[string
 	}
 }
 
-func main() {
-	// Check for version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
-		fmt.Printf("gofindpi %s (commit: %s, built: %s)\n", version, commit, date)
-		os.Exit(0)
+// selectTargets picks the target specification to scan from flags, an interactive
+// prompt, or the first available network. It also returns the interface the targets
+// were chosen from, if any.
+func selectTargets(networks []scanner.Network, opts scanOptions) (string, string, error) {
+	if opts.targets != "" {
+		return opts.targets, opts.iface, nil
+	}
+
+	if opts.iface != "" {
+		for _, n := range networks {
+			if n.Interface == opts.iface {
+				return n.Prefix.String(), n.Interface, nil
+			}
+		}
+		return "", "", fmt.Errorf("no IPv4 address found on interface %q", opts.iface)
+	}
+
+	if len(networks) == 0 {
+		return "", "", fmt.Errorf("no network interfaces found")
+	}
+
+	if !opts.interactive {
+		return networks[0].Prefix.String(), networks[0].Interface, nil
 	}
 
+	fmt.Printf("\n  %sSelect network to scan%s [%s0%s]: ", colorYellow, colorReset, colorBrightWhite, colorReset)
+	reader := bufio.NewScanner(os.Stdin)
+	reader.Scan()
+	input := strings.TrimSpace(reader.Text())
+
+	selection := 0
+	if input != "" {
+		var err error
+		selection, err = strconv.Atoi(input)
+		if err != nil || selection < 0 || selection >= len(networks) {
+			return "", "", fmt.Errorf("invalid selection %q", input)
+		}
+	}
+
+	return networks[selection].Prefix.String(), networks[selection].Interface, nil
+}
+
+// selectIPv6Interface picks the interface IPv6 discovery runs on: the one given on the
+// command line, the one IPv4 targets were chosen from, or the first usable multicast interface
+func selectIPv6Interface(opts scanOptions, chosen string) (string, error) {
+	if opts.iface != "" {
+		return opts.iface, nil
+	}
+	if chosen != "" {
+		return chosen, nil
+	}
+
+	iface, err := scanner.MulticastInterface()
+	if err != nil {
+		return "", fmt.Errorf("%w (use -interface)", err)
+	}
+	return iface, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// runScan performs a full scan using the given options
+func runScan(opts scanOptions) error {
 	printHeader()
 
 	// Set resource limits for better performance
 	setResourceLimits()
 
-	// Get local IP addresses
-	localIPs := getLocalIPs()
-	if len(localIPs) == 0 {
-		log.Fatal("No network interfaces found")
+	// Get local networks
+	networks, err := scanner.LocalNetworks()
+	if err != nil {
+		log.Printf("Error getting network interfaces: %v", err)
 	}
 
 	// Display available networks
-	printSection("AVAILABLE NETWORKS")
-	for i, ip := range localIPs {
-		fmt.Printf("  %s[%d]%s %s%s%s\n", colorBrightCyan, i, colorReset, colorWhite, ipToCIDR(ip), colorReset)
+	if len(networks) > 0 {
+		printSection("AVAILABLE NETWORKS")
+		for i, n := range networks {
+			fmt.Printf("  %s[%d]%s %s%s%s %s(%s)%s\n", colorBrightCyan, i, colorReset, colorWhite, n.Prefix, colorReset, colorDim, n.Interface, colorReset)
+		}
 	}
 
 	// Get CPU info
 	cores := getCPUCores()
 	printSection("SYSTEM INFO")
 	fmt.Printf("  %s%s%s CPU Cores: %s%d%s\n", colorDim, bullet, colorReset, colorBrightWhite, cores, colorReset)
-	fmt.Printf("  %s%s%s OUI Database: %s%d%s entries\n", colorDim, bullet, colorReset, colorBrightWhite, len(data.OUIDatabase), colorReset)
+	fmt.Printf("  %s%s%s OUI Database: %s%d%s entries\n", colorDim, bullet, colorReset, colorBrightWhite, data.EntryCount(), colorReset)
 
-	// Get user selection
-	fmt.Printf("\n  %sSelect network to scan%s [%s0%s]: ", colorYellow, colorReset, colorBrightWhite, colorReset)
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	input := strings.TrimSpace(scanner.Text())
+	scanV4 := opts.family != scanner.AddressFamilyIPv6
+	scanV6 := opts.family != scanner.AddressFamilyIPv4
 
-	selection := 0
-	if input != "" {
-		var err error
-		selection, err = strconv.Atoi(input)
-		if err != nil || selection < 0 || selection >= len(localIPs) {
-			log.Fatal("Invalid selection")
+	var (
+		targetSpec string
+		ifaceName  string
+		ips        []string
+	)
+	if scanV4 {
+		if targetSpec, ifaceName, err = selectTargets(networks, opts); err != nil {
+			return err
+		}
+	}
+	if scanV6 {
+		if ifaceName, err = selectIPv6Interface(opts, ifaceName); err != nil {
+			return err
 		}
 	}
 
-	selectedIP := localIPs[selection]
-	networkCIDR := ipToCIDR(selectedIP)
+	var labels []string
+	if scanV4 {
+		labels = append(labels, targetSpec)
+	}
+	if scanV6 {
+		labels = append(labels, "ff02::1%"+ifaceName)
+	}
+	networkCIDR := strings.Join(labels, ",")
 
 	printSection("SCANNING: " + networkCIDR)
 
-	// Configure scan parameters
-	config := scanConfig{
-		timeout:       time.Millisecond * 500,
-		maxGoroutines: cores * 32, // Balanced for network I/O
-		pingCount:     1,
+	concurrency := opts.concurrency
+	if concurrency == 0 {
+		concurrency = cores * 32 // Balanced for network I/O
 	}
 
-	// Generate IP range
-	ips := generateIPRange(selectedIP)
-	if len(ips) == 0 {
-		log.Fatal("Failed to generate IP range")
+	// Expand targets into the list of addresses to probe
+	if scanV4 {
+		ips, err = scanner.ExpandTargets(targetSpec, opts.exclude, opts.maxHosts)
+		if err != nil {
+			return err
+		}
+		if len(ips) == 0 {
+			return fmt.Errorf("no addresses left to scan in %s", targetSpec)
+		}
 	}
 
-	// Start scanning
-	startTime := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	foundIPs := scanIPRange(ctx, ips, config)
-	fmt.Printf("\n  %s%s%s Found %s%d%s active devices\n", colorGreen, checkMark, colorReset, colorBrightWhite, len(foundIPs), colorReset)
-
-	// Parse ARP table and identify devices
-	fmt.Printf("  %s%s%s Identifying manufacturers...\n", colorDim, arrowRight, colorReset)
-	devices := parseARPTable(foundIPs, true) // Enable hostname resolution
-
-	duration := time.Since(startTime)
-
-	// Calculate statistics
-	manufacturerStats, categoryStats := calculateStatistics(devices)
+	// Build the probe chain in the order given on the command line
+	var chain []scanner.Prober
+	for _, probe := range opts.probes {
+		switch probe {
+		case scanner.ProbeICMP:
+			chain = append(chain, scanner.ICMPProber{Timeout: opts.timeout, Count: opts.pingCount})
+		case scanner.ProbeTCP:
+			chain = append(chain, scanner.TCPProber{Ports: opts.tcpPorts, Timeout: opts.timeout})
+		case scanner.ProbeARP:
+			if !scanV4 {
+				continue
+			}
+			// Active ARP needs a raw socket on the interface the targets live on
+			arpIface := opts.iface
+			if arpIface == "" {
+				arpIface = scanner.InterfaceFor(networks, ips[0])
+			}
+			arp, err := scanner.NewARPProber(arpIface, opts.timeout, opts.pingCount)
+			if err != nil {
+				fmt.Printf("  %s%s%s ARP sweep unavailable: %v\n", colorYellow, crossMark, colorReset, err)
+				continue
+			}
+			defer arp.Close()
+			chain = append(chain, arp)
+		}
+	}
+	if scanV4 && len(chain) == 0 {
+		return fmt.Errorf("no usable probe methods")
+	}
 
-	// Filter Raspberry Pi devices
-	var piDevices []Device
-	for _, dev := range devices {
-		if dev.IsRaspberryPi {
-			piDevices = append(piDevices, dev)
+	// Names from earlier resolvers on the command line win
+	var resolvers []scanner.NameResolver
+	for _, name := range opts.resolvers {
+		switch name {
+		case scanner.ResolverDNS:
+			resolvers = append(resolvers, scanner.DNSResolver{Timeout: opts.dnsTimeout, Server: opts.dnsServer})
+		case scanner.ResolverNetBIOS:
+			resolvers = append(resolvers, scanner.NetBIOSResolver{Timeout: opts.timeout})
+		case scanner.ResolverLLMNR:
+			resolvers = append(resolvers, scanner.LLMNRResolver{Timeout: opts.timeout})
 		}
 	}
 
-	// Create scan result
-	result := ScanResult{
-		Timestamp:    time.Now().Format(time.RFC3339),
-		Network:      networkCIDR,
-		Duration:     duration.Seconds(),
-		TotalDevices: len(devices),
-		PiCount:      len(piDevices),
-		Devices:      devices,
-		Statistics:   manufacturerStats,
-		Categories:   categoryStats,
+	scanOpts := []scanner.Option{
+		scanner.WithTimeout(opts.timeout),
+		scanner.WithPingCount(opts.pingCount),
+		scanner.WithConcurrency(concurrency),
+		scanner.WithResolve(opts.resolve),
+		scanner.WithFingerprint(opts.fingerprint),
+		scanner.WithProbers(chain...),
+		scanner.WithResolvers(resolvers...),
+		scanner.WithResolveTimeout(opts.resolveTime),
+		scanner.WithProgress(func(completed, total int) {
+			printProgressBar(completed, total, 40)
+			if completed == total {
+				fmt.Println() // New line after progress bar
+			}
+		}),
+		scanner.WithWarningHandler(func(err error) {
+			fmt.Printf("  %s%s%s %v\n", colorRed, crossMark, colorReset, err)
+		}),
+	}
+	if scanV6 {
+		scanOpts = append(scanOpts, scanner.WithIPv6(ifaceName))
+	}
+	// Multicast goes out of the interface the targets live on
+	multicastIface := ifaceName
+	if multicastIface == "" && scanV4 {
+		multicastIface = scanner.InterfaceFor(networks, ips[0])
+	}
+	if opts.mdns {
+		scanOpts = append(scanOpts, scanner.WithMDNS(multicastIface))
+	}
+	if opts.ssdp && scanV4 {
+		scanOpts = append(scanOpts, scanner.WithSSDP(multicastIface))
+	}
+	if opts.rules != nil {
+		scanOpts = append(scanOpts, scanner.WithRules(opts.rules))
+	}
+	if len(opts.leases) > 0 {
+		scanOpts = append(scanOpts, scanner.WithLeases(opts.leases...), scanner.WithKnownOffline(opts.offline))
 	}
 
-	// Save results
-	printSection("OUTPUT FILES")
+	// Start scanning
+	ctx, cancel := context.WithTimeout(context.Background(), opts.scanTimeout)
+	defer cancel()
 
-	if len(devices) > 0 {
-		if err := writeToFile(devices, "devicesfound.txt"); err != nil {
-			fmt.Printf("  %s%s%s Failed to save devices: %v\n", colorRed, crossMark, colorReset, err)
-		} else {
-			fmt.Printf("  %s%s%s ~/devicesfound.txt %s(%d devices)%s\n", colorGreen, checkMark, colorReset, colorDim, len(devices), colorReset)
-		}
+	if scanV4 {
+		fmt.Printf("  %sScanning %d addresses...%s\n\n", colorDim, len(ips), colorReset)
+	}
+	if scanV6 {
+		fmt.Printf("  %s%s%s Pinging ff02::1%%%s...\n", colorDim, arrowRight, colorReset, ifaceName)
 	}
 
-	if err := writeJSON(result, "devicesfound.json"); err != nil {
-		fmt.Printf("  %s%s%s Failed to save JSON: %v\n", colorRed, crossMark, colorReset, err)
-	} else {
-		fmt.Printf("  %s%s%s ~/devicesfound.json %s(full scan data)%s\n", colorGreen, checkMark, colorReset, colorDim, colorReset)
+	result, err := scanner.New(scanOpts...).Scan(ctx, ips)
+	if err != nil {
+		return err
 	}
+	result.Network = networkCIDR
+	devices := result.Devices
 
-	if len(piDevices) > 0 {
-		if err := writeToFile(piDevices, "pilist.txt"); err != nil {
-			fmt.Printf("  %s%s%s Failed to save Pi list: %v\n", colorRed, crossMark, colorReset, err)
-		} else {
-			fmt.Printf("  %s%s%s ~/pilist.txt %s(%d Raspberry Pi)%s\n", colorGreen, checkMark, colorReset, colorDim, len(piDevices), colorReset)
-		}
+	fmt.Printf("\n  %s%s%s Found %s%d%s active devices\n", colorGreen, checkMark, colorReset, colorBrightWhite, len(devices), colorReset)
+
+	// Read the last scan of this network before this one joins the history
+	previous, hasPrevious := previousScan(opts.history, result.Network)
+
+	// Save results
+	if len(opts.formats) > 0 || opts.history != "" {
+		printSection("OUTPUT FILES")
+		writeOutputFiles(result, opts)
+		recordHistory(result, opts.history)
 	}
 
 	// Print device table (top 15)
@@ -771,26 +645,174 @@ func main() {
 		}
 	}
 
-	// Show Raspberry Pi devices prominently if found
-	if len(piDevices) > 0 {
-		printSection(piSymbol + " RASPBERRY PI DEVICES")
-		for _, pi := range piDevices {
-			hostInfo := ""
-			if pi.Hostname != "" {
-				hostInfo = fmt.Sprintf(" %s(%s)%s", colorDim, pi.Hostname, colorReset)
-			}
-			fmt.Printf("  %s%s%s %s%s%s%s %s[%s]%s\n",
-				colorBrightGreen, bullet, colorReset,
-				colorBrightWhite, pi.IP, colorReset, hostInfo,
-				colorDim, pi.MAC, colorReset)
-		}
+	// Show the device families being searched for prominently if found
+	for _, family := range opts.find {
+		printFamilyDevices(family, result.InFamily(family.Name))
+	}
+
+	if len(result.Offline) > 0 {
+		printOfflineDevices(result.Offline)
+	}
+
+	if hasPrevious {
+		printChanges(history.Compare(previous, result))
 	}
 
 	// Print statistics
-	printStatistics(devices, len(piDevices), manufacturerStats, categoryStats)
+	printStatistics(devices, result.PiCount, result.Statistics, result.Categories)
 
 	// Footer
 	fmt.Printf("\n%s%s%s\n", colorDim, strings.Repeat(lineHorizontal, 64), colorReset)
-	fmt.Printf("  %sScan completed in %s%.2f seconds%s\n", colorDim, colorBrightWhite, duration.Seconds(), colorReset)
+	fmt.Printf("  %sScan completed in %s%.2f seconds%s\n", colorDim, colorBrightWhite, result.Duration, colorReset)
 	fmt.Println()
+
+	return nil
+}
+
+// printFamilyDevices lists the members of a device family with their fingerprint estimates
+func printFamilyDevices(family scanner.DeviceFamily, members []scanner.Device) {
+	if len(members) == 0 {
+		return
+	}
+
+	title := strings.ToUpper(family.Label) + " DEVICES"
+	if family.Name == scanner.FamilyRaspberryPi {
+		title = piSymbol + " " + title
+	}
+	printSection(title)
+	for _, dev := range members {
+		hostInfo := ""
+		if dev.Hostname != "" {
+			hostInfo = fmt.Sprintf(" %s(%s)%s", colorDim, dev.Hostname, colorReset)
+		}
+		fmt.Printf("  %s%s%s %s%s%s%s %s[%s]%s\n",
+			colorBrightGreen, bullet, colorReset,
+			colorBrightWhite, dev.IP, colorReset, hostInfo,
+			colorDim, dev.MAC, colorReset)
+		var details []string
+		for _, detail := range []string{dev.PiModel, dev.OS} {
+			if detail != "" {
+				details = append(details, detail)
+			}
+		}
+		if len(details) > 0 {
+			fmt.Printf("    %s%s%s\n", colorGreen, strings.Join(details, ", "), colorReset)
+		}
+	}
+}
+
+// printOfflineDevices lists devices that hold a DHCP lease but did not answer
+func printOfflineDevices(devices []scanner.Device) {
+	printSection("KNOWN OFFLINE (DHCP LEASES)")
+	for _, dev := range devices {
+		hostInfo := ""
+		if dev.Hostname != "" {
+			hostInfo = fmt.Sprintf(" %s(%s)%s", colorDim, dev.Hostname, colorReset)
+		}
+		expiry := "never expires"
+		if !dev.Lease.Expires.IsZero() {
+			expiry = "expires " + dev.Lease.Expires.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  %s%s%s %s%s %s[%s] %s%s\n",
+			colorDim, bullet, colorReset,
+			dev.IP, hostInfo,
+			colorDim, dev.MAC, expiry, colorReset)
+	}
+}
+
+// previousScan returns the most recent scan of network in the history file, if any
+func previousScan(path, network string) (scanner.ScanResult, bool) {
+	if path == "" {
+		return scanner.ScanResult{}, false
+	}
+	previous, ok, err := history.Open(path).Latest(network)
+	if err != nil {
+		fmt.Printf("  %s%s%s Failed to read history: %v\n", colorRed, crossMark, colorReset, err)
+		return scanner.ScanResult{}, false
+	}
+	return previous, ok
+}
+
+// printChanges lists the devices that appeared, vanished or changed since the last scan
+func printChanges(d history.Diff) {
+	printSection("CHANGES SINCE LAST SCAN")
+	since := d.From
+	if t, err := time.Parse(time.RFC3339, d.From); err == nil {
+		since = formatHistoryTime(t)
+	}
+	if d.Empty() {
+		fmt.Printf("  %sNo changes since %s%s\n", colorDim, since, colorReset)
+		return
+	}
+	for _, dev := range d.New {
+		fmt.Printf("  %s+%s %s %s(new)%s\n", colorBrightGreen, colorReset, describeDevice(dev), colorDim, colorReset)
+	}
+	for _, dev := range d.Missing {
+		fmt.Printf("  %s-%s %s %s(missing)%s\n", colorRed, colorReset, describeDevice(dev), colorDim, colorReset)
+	}
+	for _, c := range d.Changed {
+		fmt.Printf("  %s~%s %s\n", colorYellow, colorReset, describeDevice(c.Device))
+		fmt.Printf("    %s%s%s\n", colorYellow, describeChanges(c.Changes, arrowRight), colorReset)
+	}
+	fmt.Printf("\n  %s%d new, %d missing, %d changed since %s%s\n",
+		colorDim, len(d.New), len(d.Missing), len(d.Changed), since, colorReset)
+}
+
+// recordHistory appends the scan to the history file, if one is configured
+func recordHistory(result scanner.ScanResult, path string) {
+	if path == "" {
+		return
+	}
+	if err := history.Open(path).Append(result); err != nil {
+		fmt.Printf("  %s%s%s Failed to record history: %v\n", colorRed, crossMark, colorReset, err)
+		return
+	}
+	fmt.Printf("  %s%s%s %s %s(scan history)%s\n", colorGreen, checkMark, colorReset, path, colorDim, colorReset)
+}
+
+// familyListFile names the text output listing a device family's members. The
+// Raspberry Pi list keeps its original name.
+func familyListFile(family string) string {
+	if family == scanner.FamilyRaspberryPi {
+		return "pilist.txt"
+	}
+	return family + "list.txt"
+}
+
+// writeOutputFiles saves the requested output formats and reports each file written
+func writeOutputFiles(result scanner.ScanResult, opts scanOptions) {
+	dir, err := outputDir(opts.outputDir)
+	if err != nil {
+		fmt.Printf("  %s%s%s %v\n", colorRed, crossMark, colorReset, err)
+		return
+	}
+
+	if opts.hasFormat("text") && len(result.Devices) > 0 {
+		if err := writeToFile(result.Devices, dir, "devicesfound.txt"); err != nil {
+			fmt.Printf("  %s%s%s Failed to save devices: %v\n", colorRed, crossMark, colorReset, err)
+		} else {
+			fmt.Printf("  %s%s%s %s %s(%d devices)%s\n", colorGreen, checkMark, colorReset, filepath.Join(dir, "devicesfound.txt"), colorDim, len(result.Devices), colorReset)
+		}
+	}
+
+	if opts.hasFormat("json") {
+		if err := writeJSON(result, dir, "devicesfound.json"); err != nil {
+			fmt.Printf("  %s%s%s Failed to save JSON: %v\n", colorRed, crossMark, colorReset, err)
+		} else {
+			fmt.Printf("  %s%s%s %s %s(full scan data)%s\n", colorGreen, checkMark, colorReset, filepath.Join(dir, "devicesfound.json"), colorDim, colorReset)
+		}
+	}
+
+	for _, family := range opts.find {
+		members := result.InFamily(family.Name)
+		if !opts.hasFormat("text") || len(members) == 0 {
+			continue
+		}
+		fileName := familyListFile(family.Name)
+		if err := writeToFile(members, dir, fileName); err != nil {
+			fmt.Printf("  %s%s%s Failed to save %s list: %v\n", colorRed, crossMark, colorReset, family.Label, err)
+		} else {
+			fmt.Printf("  %s%s%s %s %s(%d %s)%s\n", colorGreen, checkMark, colorReset, filepath.Join(dir, fileName), colorDim, len(members), family.Label, colorReset)
+		}
+	}
 }
@@ -0,0 +1,16 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errARPUnsupported is returned where active ARP sweeps are not implemented
+var errARPUnsupported = errors.New("ARP sweep is only supported on Linux")
+
+// arpResolver sends up to attempts ARP requests, waiting timeout for each, and reports the MAC address of hosts that reply
+type arpResolver interface {
+	resolve(ctx context.Context, ip string, timeout time.Duration, attempts int) (string, bool)
+	Close() error
+}
@@ -0,0 +1,242 @@
+//go:build linux
+
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	arpFrameLen  = 42 // Ethernet header (14) + ARP payload (28)
+	arpOpRequest = 1
+	arpOpReply   = 2
+)
+
+// linuxARPResolver sends ARP requests over a raw AF_PACKET socket bound to one
+// interface. A single reader goroutine collects replies and wakes waiting probes.
+type linuxARPResolver struct {
+	fd      int
+	ifindex int
+	srcMAC  net.HardwareAddr
+	srcIP   netip.Addr
+
+	mu      sync.Mutex
+	replies map[netip.Addr]string
+	waiters map[netip.Addr][]chan string
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newARPResolver opens a raw ARP socket on the given interface. It fails with a
+// descriptive error when the process lacks CAP_NET_RAW.
+func newARPResolver(ifaceName string) (arpResolver, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown interface %q: %w", ifaceName, err)
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("interface %s has no Ethernet address", iface.Name)
+	}
+
+	srcIP, err := interfaceIPv4(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			return nil, fmt.Errorf("ARP sweep needs raw socket access: run as root or grant CAP_NET_RAW (setcap cap_net_raw+ep gofindpi)")
+		}
+		return nil, fmt.Errorf("failed to open ARP socket: %w", err)
+	}
+
+	sll := &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ARP), Ifindex: iface.Index}
+	if err := syscall.Bind(fd, sll); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind ARP socket to %s: %w", iface.Name, err)
+	}
+
+	// A short receive timeout lets the reader notice Close without extra machinery
+	tv := syscall.NsecToTimeval((100 * time.Millisecond).Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to configure ARP socket: %w", err)
+	}
+
+	r := &linuxARPResolver{
+		fd:      fd,
+		ifindex: iface.Index,
+		srcMAC:  iface.HardwareAddr,
+		srcIP:   srcIP,
+		replies: make(map[netip.Addr]string),
+		waiters: make(map[netip.Addr][]chan string),
+		done:    make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.readLoop()
+	return r, nil
+}
+
+// interfaceIPv4 returns the first IPv4 address assigned to an interface
+func interfaceIPv4(iface *net.Interface) (netip.Addr, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to read addresses of %s: %w", iface.Name, err)
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			if addr, ok := netip.AddrFromSlice(ipnet.IP.To4()); ok {
+				return addr, nil
+			}
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("interface %s has no IPv4 address", iface.Name)
+}
+
+// resolve broadcasts who-has requests for ip until a reply arrives or every attempt times out
+func (r *linuxARPResolver) resolve(ctx context.Context, ip string, timeout time.Duration, attempts int) (string, bool) {
+	target, err := netip.ParseAddr(ip)
+	if err != nil || !target.Is4() {
+		return "", false
+	}
+
+	ch := make(chan string, 1)
+	r.mu.Lock()
+	if mac, ok := r.replies[target]; ok {
+		r.mu.Unlock()
+		return mac, true
+	}
+	r.waiters[target] = append(r.waiters[target], ch)
+	r.mu.Unlock()
+	defer r.removeWaiter(target, ch)
+
+	frame := r.buildRequest(target)
+	sll := &syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_ARP),
+		Ifindex:  r.ifindex,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err := syscall.Sendto(r.fd, frame, 0, sll); err != nil {
+			return "", false
+		}
+
+		timer := time.NewTimer(timeout)
+		select {
+		case mac := <-ch:
+			timer.Stop()
+			return mac, true
+		case <-ctx.Done():
+			timer.Stop()
+			return "", false
+		case <-timer.C:
+		}
+	}
+	return "", false
+}
+
+// buildRequest assembles a broadcast Ethernet frame carrying an ARP who-has request
+func (r *linuxARPResolver) buildRequest(target netip.Addr) []byte {
+	frame := make([]byte, arpFrameLen)
+
+	// Ethernet header
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], r.srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], syscall.ETH_P_ARP)
+
+	// ARP payload
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)      // Hardware type: Ethernet
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800) // Protocol type: IPv4
+	arp[4] = 6                                   // Hardware address length
+	arp[5] = 4                                   // Protocol address length
+	binary.BigEndian.PutUint16(arp[6:8], arpOpRequest)
+	copy(arp[8:14], r.srcMAC)
+	src := r.srcIP.As4()
+	copy(arp[14:18], src[:])
+	// Target hardware address (arp[18:24]) stays zero
+	dst := target.As4()
+	copy(arp[24:28], dst[:])
+
+	return frame
+}
+
+// readLoop records every ARP reply seen on the interface until Close is called
+func (r *linuxARPResolver) readLoop() {
+	defer r.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(r.fd, buf, 0)
+		if err != nil || n < arpFrameLen {
+			continue
+		}
+
+		arp := buf[14:n]
+		if binary.BigEndian.Uint16(buf[12:14]) != syscall.ETH_P_ARP ||
+			binary.BigEndian.Uint16(arp[6:8]) != arpOpReply {
+			continue
+		}
+
+		sender, ok := netip.AddrFromSlice(arp[14:18])
+		if !ok {
+			continue
+		}
+		mac := net.HardwareAddr(arp[8:14]).String()
+
+		r.mu.Lock()
+		r.replies[sender] = mac
+		for _, ch := range r.waiters[sender] {
+			select {
+			case ch <- mac:
+			default:
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+// removeWaiter unregisters a probe that is no longer waiting for a reply
+func (r *linuxARPResolver) removeWaiter(target netip.Addr, ch chan string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	waiters := r.waiters[target]
+	for i, w := range waiters {
+		if w == ch {
+			r.waiters[target] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(r.waiters[target]) == 0 {
+		delete(r.waiters, target)
+	}
+}
+
+// Close stops the reader and releases the socket
+func (r *linuxARPResolver) Close() error {
+	close(r.done)
+	r.wg.Wait()
+	return syscall.Close(r.fd)
+}
+
+// htons converts a 16-bit value to network byte order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
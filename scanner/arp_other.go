@@ -0,0 +1,8 @@
+//go:build !linux
+
+package scanner
+
+// newARPResolver is unavailable outside Linux, where raw AF_PACKET sockets exist
+func newARPResolver(string) (arpResolver, error) {
+	return nil, errARPUnsupported
+}
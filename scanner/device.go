@@ -0,0 +1,137 @@
+package scanner
+
+import "github.com/james-see/gofindpi/data"
+
+// Device represents a discovered network device with full identification
+type Device struct {
+	IP                  string  `json:"ip"`
+	MAC                 string  `json:"mac"`
+	Manufacturer        string  `json:"manufacturer"`
+	Category            string  `json:"category"`
+	IsRaspberryPi       bool    `json:"is_raspberry_pi"`
+	DeviceFamily        string  `json:"device_family,omitempty"`
+	Hostname            string  `json:"hostname,omitempty"`
+	HostnameSource      string  `json:"hostname_source,omitempty"`
+	Workgroup           string  `json:"workgroup,omitempty"`
+	MDNSName            string  `json:"mdns_name,omitempty"`
+	AddressFamily       string  `json:"address_family"`
+	LocallyAdministered bool    `json:"locally_administered"`
+	Multicast           bool    `json:"multicast"`
+	CategoryRule        string  `json:"category_rule,omitempty"`
+	OpenPorts           []int   `json:"open_ports,omitempty"`
+	DiscoveredBy        string  `json:"discovered_by,omitempty"`
+	Evidence            string  `json:"evidence,omitempty"`
+	RTTMillis           float64 `json:"rtt_ms,omitempty"`
+
+	// DNS-SD services announced over mDNS during the scan
+	Services []Service `json:"services,omitempty"`
+
+	// UPnP device description, for devices that answered the SSDP search
+	UPnP *UPnP `json:"upnp,omitempty"`
+
+	// DHCP lease of the device, when lease files were given
+	Lease *Lease `json:"dhcp_lease,omitempty"`
+
+	// Estimates from fingerprinting, with the clues they are based on
+	PiModel     string   `json:"pi_model,omitempty"`
+	OS          string   `json:"os,omitempty"`
+	Fingerprint []string `json:"fingerprint,omitempty"`
+}
+
+// ScanResult contains the complete scan results with metadata
+type ScanResult struct {
+	Timestamp    string         `json:"timestamp"`
+	Network      string         `json:"network"`
+	Duration     float64        `json:"duration_seconds"`
+	TotalDevices int            `json:"total_devices"`
+	PiCount      int            `json:"raspberry_pi_count"`
+	Devices      []Device       `json:"devices"`
+	Statistics   map[string]int `json:"manufacturer_statistics"`
+	Categories   map[string]int `json:"category_statistics"`
+	Families     map[string]int `json:"device_family_statistics,omitempty"`
+
+	// Devices with an active DHCP lease on a scanned address that did not answer,
+	// when enabled with WithKnownOffline. They are not counted in the totals.
+	Offline []Device `json:"known_offline,omitempty"`
+}
+
+// RaspberryPis returns the devices identified as Raspberry Pis
+func (r ScanResult) RaspberryPis() []Device {
+	var piDevices []Device
+	for _, dev := range r.Devices {
+		if dev.IsRaspberryPi {
+			piDevices = append(piDevices, dev)
+		}
+	}
+	return piDevices
+}
+
+// InFamily returns the devices assigned to the named device family
+func (r ScanResult) InFamily(name string) []Device {
+	var members []Device
+	for _, dev := range r.Devices {
+		if dev.DeviceFamily == name {
+			members = append(members, dev)
+		}
+	}
+	return members
+}
+
+// LookupManufacturer retrieves manufacturer info from the OUI database. See
+// data.Lookup for the accepted MAC notations and a more detailed result.
+func LookupManufacturer(mac string) (data.ManufacturerInfo, bool) {
+	result, ok, _ := data.Lookup(mac)
+	return data.ManufacturerInfo{Name: result.Manufacturer, Category: result.Category}, ok
+}
+
+// IsRaspberryPi checks if the device is a Raspberry Pi based on MAC prefix
+func IsRaspberryPi(mac string) bool {
+	result, _, _ := data.Lookup(mac)
+	return result.IsRaspberryPi
+}
+
+// newDevice builds a Device from an address and MAC, identifying its manufacturer
+func newDevice(ip, mac, family string) Device {
+	// Lookup manufacturer info
+	info, _, _ := data.Lookup(mac)
+
+	// Check if Raspberry Pi
+	if info.IsRaspberryPi {
+		info.Category = "Raspberry Pi"
+	}
+
+	return Device{
+		IP:                  ip,
+		MAC:                 mac,
+		Manufacturer:        info.Manufacturer,
+		Category:            info.Category,
+		IsRaspberryPi:       info.IsRaspberryPi,
+		AddressFamily:       family,
+		LocallyAdministered: info.LocallyAdministered,
+		Multicast:           info.Multicast,
+	}
+}
+
+// familyStatistics counts devices by device family, omitting those in none
+func familyStatistics(devices []Device) map[string]int {
+	stats := make(map[string]int)
+	for _, dev := range devices {
+		if dev.DeviceFamily != "" {
+			stats[dev.DeviceFamily]++
+		}
+	}
+	return stats
+}
+
+// Statistics counts devices by manufacturer and by category
+func Statistics(devices []Device) (map[string]int, map[string]int) {
+	manufacturerStats := make(map[string]int)
+	categoryStats := make(map[string]int)
+
+	for _, dev := range devices {
+		manufacturerStats[dev.Manufacturer]++
+		categoryStats[dev.Category]++
+	}
+
+	return manufacturerStats, categoryStats
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/james-see/gofindpi/data"
+)
+
+// FamilyRaspberryPi names the built-in Raspberry Pi device family
+const FamilyRaspberryPi = "raspberry-pi"
+
+// DeviceFamily is a kind of board to pick out of a scan, such as Raspberry Pis or
+// ESP32 modules. A device belongs to a family when any of its heuristics match.
+type DeviceFamily struct {
+	Name     string            // Identifier used by -find and in output, e.g. "esp32"
+	Label    string            // Display name, e.g. "ESP32/ESP8266"
+	OUIs     []string          // 24-bit MAC prefixes, e.g. "b8:27:eb"
+	Vendors  []string          // Manufacturer names, matched case-insensitively as substrings
+	Hostname *regexp.Regexp    // Default or conventional hostnames, matched against the first label
+	Match    func(Device) bool // Any other heuristic; may be nil
+}
+
+// DeviceFamilies are the built-in families. A device is assigned the first family
+// that matches, so more specific families come first.
+var DeviceFamilies = []DeviceFamily{
+	{
+		Name:     FamilyRaspberryPi,
+		Label:    "Raspberry Pi",
+		OUIs:     data.RaspberryPiOUIs,
+		Hostname: regexp.MustCompile(`^raspberrypi`),
+		Match:    func(dev Device) bool { return dev.IsRaspberryPi }, // Set by fingerprinting
+	},
+	{
+		Name:     "esp32",
+		Label:    "ESP32/ESP8266",
+		Vendors:  []string{"Espressif"},
+		Hostname: regexp.MustCompile(`^(esp[-_]|esp32|esp8266|espressif|tasmota-|wled-)`),
+	},
+	{
+		// NVIDIA MACs are also found in Shield TVs and network cards, so only the
+		// default hostnames count
+		Name:     "jetson",
+		Label:    "NVIDIA Jetson",
+		Hostname: regexp.MustCompile(`^(jetson|xavier|orin)([-_\d]|$)`),
+	},
+	{
+		// BeagleBones carry Texas Instruments MACs, which are shared with far too many
+		// other products to be useful, so only the default hostnames count
+		Name:     "beaglebone",
+		Label:    "BeagleBone",
+		Hostname: regexp.MustCompile(`^(beaglebone|pocketbeagle|beagle)`),
+	},
+	{
+		Name:     "arduino",
+		Label:    "Arduino",
+		Vendors:  []string{"Arduino"},
+		Hostname: regexp.MustCompile(`^arduino`),
+	},
+}
+
+// LookupDeviceFamily finds a built-in family by name
+func LookupDeviceFamily(name string) (DeviceFamily, bool) {
+	for _, f := range DeviceFamilies {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return DeviceFamily{}, false
+}
+
+// Matches reports whether a device belongs to the family
+func (f DeviceFamily) Matches(dev Device) bool {
+	if len(dev.MAC) >= 8 && slices.Contains(f.OUIs, dev.MAC[:8]) {
+		return true
+	}
+	manufacturer := strings.ToLower(dev.Manufacturer)
+	for _, vendor := range f.Vendors {
+		if strings.Contains(manufacturer, strings.ToLower(vendor)) {
+			return true
+		}
+	}
+	if f.Hostname != nil && dev.Hostname != "" {
+		label, _, _ := strings.Cut(strings.ToLower(dev.Hostname), ".")
+		if f.Hostname.MatchString(label) {
+			return true
+		}
+	}
+	return f.Match != nil && f.Match(dev)
+}
+
+// detectFamilies assigns each device the first family it matches. Members of the
+// Raspberry Pi family are flagged as Pis, whichever heuristic found them.
+func detectFamilies(families []DeviceFamily, devices []Device) {
+	for i := range devices {
+		dev := &devices[i]
+		for _, f := range families {
+			if !f.Matches(*dev) {
+				continue
+			}
+			dev.DeviceFamily = f.Name
+			if f.Name == FamilyRaspberryPi && !dev.IsRaspberryPi {
+				dev.IsRaspberryPi = true
+				dev.Category = "Raspberry Pi"
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package scanner
+
+import "testing"
+
+func TestDeviceFamilyMatches(t *testing.T) {
+	tests := []struct {
+		dev  Device
+		want string // Family assigned, or "" for none
+	}{
+		{Device{MAC: "b8:27:eb:12:34:56"}, FamilyRaspberryPi},
+		{Device{Hostname: "raspberrypi.local"}, FamilyRaspberryPi},
+		{Device{Manufacturer: "Espressif Inc."}, "esp32"},
+		{Device{Hostname: "tasmota-4a2b3c"}, "esp32"},
+		{Device{Hostname: "jetson-nano.lan"}, "jetson"},
+		{Device{Hostname: "jetson"}, "jetson"},
+		{Device{Hostname: "orin1"}, "jetson"},
+		{Device{Hostname: "xavier_nx"}, "jetson"},
+		{Device{Hostname: "florin-laptop"}, ""},
+		{Device{Hostname: "corinne"}, ""},
+		{Device{Hostname: "dorinda.local"}, ""},
+		{Device{Hostname: "orinoco"}, ""},
+		{Device{Manufacturer: "NVIDIA Corporation", Hostname: "shield"}, ""},
+		{Device{Hostname: "beaglebone"}, "beaglebone"},
+		{Device{Manufacturer: "Arduino AG"}, "arduino"},
+	}
+	for _, tt := range tests {
+		devices := []Device{tt.dev}
+		detectFamilies(DeviceFamilies, devices)
+		if got := devices[0].DeviceFamily; got != tt.want {
+			t.Errorf("%+v: family %q, want %q", tt.dev, got, tt.want)
+		}
+	}
+}
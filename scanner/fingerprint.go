@@ -0,0 +1,298 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-see/gofindpi/data"
+)
+
+// Fingerprinting goes beyond the six Raspberry Pi OUIs: hostnames, mDNS service
+// announcements and the SSH banner identify Pis with a randomized or unknown MAC and
+// narrow down the model and operating system. The result is an estimate, and the
+// clues it is based on are listed in Device.Fingerprint.
+
+// piModelsByOUI lists the boards each Raspberry Pi OUI has been seen on
+var piModelsByOUI = map[string]string{
+	"b8:27:eb": "Raspberry Pi 1/2/3/Zero",
+	"dc:a6:32": "Raspberry Pi 4",
+	"e4:5f:01": "Raspberry Pi 4/400/Zero 2 W",
+	"28:cd:c1": "Raspberry Pi 4/400",
+	"d8:3a:dd": "Raspberry Pi 4/5",
+	"2c:cf:67": "Raspberry Pi 5/500",
+}
+
+// piHostnamePatterns match model hints in hostnames such as "rpi-3", "raspberrypi5",
+// "pi4-kitchen" or "pi-zero2w". After a bare "pi" only names that are not also node
+// numbers count, so cluster hosts "pi-1" to "pi-5" or "pi1" to "pi3" are not taken
+// for models.
+var piHostnamePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|[^a-z])(?:raspberry-?pi|rpi)-?(zero-?2(?:-?w)?|zero(?:-?w)?|500|400|[1-5])(?:[^0-9]|$)`),
+	regexp.MustCompile(`(?:^|[^a-z])pi(-?zero-?2(?:-?w)?|-?zero(?:-?w)?|500|400|[45])(?:[^0-9]|$)`),
+}
+
+// workstationPattern splits an Avahi workstation instance name, "host [mac]"
+var workstationPattern = regexp.MustCompile(`^(.*) \[([0-9a-fA-F:]{17})\]$`)
+
+// debianReleases maps the "+debNN" suffix of Debian package versions to a release
+var debianReleases = map[string]string{
+	"8":  "jessie",
+	"9":  "stretch",
+	"10": "buster",
+	"11": "bullseye",
+	"12": "bookworm",
+	"13": "trixie",
+}
+
+var debianSuffixPattern = regexp.MustCompile(`\+deb(\d+)u\d+`)
+
+// fingerprintAll fingerprints devices concurrently
+func (s *Scanner) fingerprintAll(ctx context.Context, devices []Device) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, s.concurrency)
+	for i := range devices {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(dev *Device) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fingerprintDevice(ctx, dev, s.timeout)
+		}(&devices[i])
+	}
+	wg.Wait()
+}
+
+// fingerprintDevice queries a device over mDNS and SSH and records what it learns
+func fingerprintDevice(ctx context.Context, dev *Device, timeout time.Duration) {
+	var (
+		wg     sync.WaitGroup
+		mdns   mdnsRecords
+		banner string
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		mdns, _ = queryMDNS(ctx, dev.IP, timeout)
+	}()
+	go func() {
+		defer wg.Done()
+		banner, _ = readSSHBanner(ctx, dev.IP, timeout)
+	}()
+	wg.Wait()
+
+	// Services heard while browsing count as if the device had answered the query
+	for _, svc := range dev.Services {
+		mdns.Instances = appendUnique(mdns.Instances, svc.Instance+"."+svc.Type+".local")
+		for _, txt := range svc.TXT {
+			mdns.TXT = appendUnique(mdns.TXT, txt)
+		}
+	}
+	applyFingerprint(dev, mdns, banner)
+}
+
+// readSSHBanner returns the identification line an SSH server sends on connect,
+// e.g. "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3"
+func readSSHBanner(ctx context.Context, ip string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, "22"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Servers may send other lines before the identification string (RFC 4253 4.2)
+	reader := bufio.NewScanner(conn)
+	for reader.Scan() {
+		if line := strings.TrimSpace(reader.Text()); strings.HasPrefix(line, "SSH-") {
+			return line, nil
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no SSH banner", ip)
+}
+
+// applyFingerprint combines the OUI with the hostnames, mDNS records and SSH banner
+// of a device into an estimate of whether it is a Raspberry Pi, which model and
+// which operating system it runs. Explicit model announcements beat hostname hints,
+// which beat the boards known for the OUI.
+func applyFingerprint(dev *Device, mdns mdnsRecords, banner string) {
+	var (
+		clues         []string
+		isPi          = dev.IsRaspberryPi
+		piOUI         string // Pi OUI of the device or of the MAC it announces
+		announced     string // Model from an mDNS TXT record
+		hostnameModel string
+		osName        string
+		defaultName   bool // The Raspberry Pi OS default hostname
+	)
+
+	if dev.Hostname == "" && len(mdns.Hostnames) > 0 {
+		dev.Hostname, dev.HostnameSource = mdns.Hostnames[0], HostnameMDNS
+	}
+
+	if data.IsRaspberryPiOUI(prefixOf(dev.MAC)) {
+		piOUI = prefixOf(dev.MAC)
+		clues = append(clues, "oui "+piOUI+" ("+piModelsByOUI[piOUI]+")")
+	}
+
+	var names []string
+	for _, name := range append([]string{dev.Hostname, dev.MDNSName}, mdns.Hostnames...) {
+		if name != "" {
+			names = appendUnique(names, name)
+		}
+	}
+	for _, instance := range mdns.Instances {
+		name, _, _ := strings.Cut(instance, "._")
+		if m := workstationPattern.FindStringSubmatch(name); m != nil {
+			name = m[1]
+			if mac := strings.ToLower(m[2]); data.IsRaspberryPiOUI(prefixOf(mac)) && piOUI == "" {
+				isPi, piOUI = true, prefixOf(mac)
+				clues = append(clues, "mdns workstation MAC "+mac)
+			}
+		}
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		label, _, _ := strings.Cut(strings.ToLower(name), ".")
+		if strings.HasPrefix(label, "raspberrypi") && !defaultName {
+			defaultName, isPi = true, true
+			clues = appendUnique(clues, "hostname "+name)
+		}
+		if model := hostnameModelHint(label); model != "" && hostnameModel == "" {
+			hostnameModel, isPi = model, true
+			clues = appendUnique(clues, "hostname "+name)
+		}
+	}
+
+	for _, txt := range mdns.TXT {
+		key, value, ok := strings.Cut(txt, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "model":
+			if strings.Contains(strings.ToLower(value), "raspberry pi") && announced == "" {
+				announced, isPi = value, true
+				clues = append(clues, "mdns "+txt)
+			}
+		case "os", "distro":
+			if osName == "" {
+				osName = value
+				clues = append(clues, "mdns "+txt)
+			}
+		}
+	}
+
+	if banner != "" {
+		if name, raspbian := parseSSHBanner(banner); name != "" {
+			osName = name // The banner names the exact package build, so it wins
+			isPi = isPi || raspbian
+			clues = append(clues, "ssh "+banner)
+		}
+	}
+	if osName == "" && defaultName {
+		osName = "Raspberry Pi OS"
+	}
+	dev.OS = osName
+
+	if !isPi {
+		dev.Fingerprint = clues
+		return
+	}
+
+	switch {
+	case announced != "":
+		dev.PiModel = announced
+	case hostnameModel != "":
+		dev.PiModel = hostnameModel
+	default:
+		dev.PiModel = piModelsByOUI[piOUI]
+	}
+	if !dev.IsRaspberryPi {
+		dev.IsRaspberryPi = true
+		dev.Category = "Raspberry Pi"
+	}
+	dev.Fingerprint = clues
+}
+
+// hostnameModelHint returns the model a hostname label suggests, if any
+func hostnameModelHint(label string) string {
+	var hint string
+	for _, pattern := range piHostnamePatterns {
+		if m := pattern.FindStringSubmatch(label); m != nil {
+			hint = strings.ReplaceAll(m[1], "-", "")
+			break
+		}
+	}
+	switch {
+	case hint == "":
+		return ""
+	case strings.HasPrefix(hint, "zero2"):
+		return "Raspberry Pi Zero 2 W"
+	case strings.HasPrefix(hint, "zero"):
+		return "Raspberry Pi Zero"
+	default:
+		return "Raspberry Pi " + hint
+	}
+}
+
+// parseSSHBanner estimates the operating system from an SSH identification string.
+// Debian-based systems name their package version, e.g.
+//
+//	SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3     Debian 12 (bookworm)
+//	SSH-2.0-OpenSSH_7.4p1 Raspbian-10+deb9u7   Raspbian (Debian 9 stretch)
+//
+// raspbian reports whether the banner names Raspbian, which only runs on Pis.
+func parseSSHBanner(banner string) (osName string, raspbian bool) {
+	number, codename := "", ""
+	if m := debianSuffixPattern.FindStringSubmatch(banner); m != nil {
+		number, codename = m[1], debianReleases[m[1]]
+	}
+
+	lower := strings.ToLower(banner)
+	switch {
+	case strings.Contains(lower, "raspbian"):
+		if number == "" {
+			return "Raspbian", true
+		}
+		return "Raspbian (" + strings.TrimSpace("Debian "+number+" "+codename) + ")", true
+	case strings.Contains(lower, "debian"):
+		switch {
+		case codename != "":
+			return "Debian " + number + " (" + codename + ")", false
+		case number != "":
+			return "Debian " + number, false
+		}
+		return "Debian", false
+	case strings.Contains(lower, "ubuntu"):
+		return "Ubuntu", false
+	case strings.Contains(lower, "freebsd"):
+		return "FreeBSD", false
+	case strings.Contains(lower, "for_windows"):
+		return "Windows", false
+	}
+	return "", false
+}
+
+// prefixOf returns the OUI of a normalized MAC address
+func prefixOf(mac string) string {
+	if len(mac) < 8 {
+		return ""
+	}
+	return mac[:8]
+}
@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHostnameModelHint(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"rpi-3", "Raspberry Pi 3"},
+		{"rpi3", "Raspberry Pi 3"},
+		{"raspberrypi5", "Raspberry Pi 5"},
+		{"raspberry-pi-1", "Raspberry Pi 1"},
+		{"kitchen-rpi-400", "Raspberry Pi 400"},
+		{"rpi-zero2w", "Raspberry Pi Zero 2 W"},
+		{"rpi-zero-2-w", "Raspberry Pi Zero 2 W"},
+		{"rpizerow", "Raspberry Pi Zero"},
+		{"pi4-kitchen", "Raspberry Pi 4"},
+		{"pi5", "Raspberry Pi 5"},
+		{"pi400", "Raspberry Pi 400"},
+		{"pizero2w", "Raspberry Pi Zero 2 W"},
+		{"pi-zero", "Raspberry Pi Zero"},
+		{"office-pi500", "Raspberry Pi 500"},
+		// Cluster node numbers are not models
+		{"pi-1", ""},
+		{"pi-3", ""},
+		{"pi-5", ""},
+		{"pi1", ""},
+		{"pi2", ""},
+		{"pi3", ""},
+		{"pi-4-node", ""},
+		// Nor are longer numbers or words that merely contain the letters
+		{"pi42", ""},
+		{"rpi-10", ""},
+		{"raspberrypi", ""},
+		{"api4", ""},
+		{"spi5", ""},
+		{"pixel5", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := hostnameModelHint(tt.label); got != tt.want {
+			t.Errorf("hostnameModelHint(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestParseSSHBanner(t *testing.T) {
+	tests := []struct {
+		banner   string
+		os       string
+		raspbian bool
+	}{
+		{"SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3", "Debian 12 (bookworm)", false},
+		{"SSH-2.0-OpenSSH_8.4p1 Debian-5+deb11u1", "Debian 11 (bullseye)", false},
+		{"SSH-2.0-OpenSSH_7.4p1 Raspbian-10+deb9u7", "Raspbian (Debian 9 stretch)", true},
+		{"SSH-2.0-OpenSSH_6.0p1 Raspbian-4+deb7u2", "Raspbian (Debian 7)", true},
+		{"SSH-2.0-OpenSSH_6.0p1 Raspbian-4", "Raspbian", true},
+		{"SSH-2.0-OpenSSH_9.9p1 Debian-1+deb99u1", "Debian 99", false},
+		{"SSH-2.0-OpenSSH_9.2p1 Debian-2", "Debian", false},
+		{"SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", "Ubuntu", false},
+		{"SSH-2.0-OpenSSH_9.3 FreeBSD-20230719", "FreeBSD", false},
+		{"SSH-2.0-OpenSSH_for_Windows_8.1", "Windows", false},
+		{"SSH-2.0-dropbear_2022.83", "", false},
+		{"SSH-2.0-OpenSSH_9.6", "", false},
+	}
+	for _, tt := range tests {
+		os, raspbian := parseSSHBanner(tt.banner)
+		if os != tt.os || raspbian != tt.raspbian {
+			t.Errorf("parseSSHBanner(%q) = %q, %v; want %q, %v", tt.banner, os, raspbian, tt.os, tt.raspbian)
+		}
+	}
+}
+
+func TestApplyFingerprint(t *testing.T) {
+	tests := []struct {
+		name   string
+		dev    Device
+		mdns   mdnsRecords
+		banner string
+		want   Device // Only the fields fingerprinting sets are compared
+	}{
+		{
+			name: "OUI alone",
+			dev:  newDevice("10.0.0.2", "dc:a6:32:00:00:01", AddressFamilyIPv4),
+			want: Device{IsRaspberryPi: true, Category: "Raspberry Pi", PiModel: "Raspberry Pi 4",
+				Fingerprint: []string{"oui dc:a6:32 (Raspberry Pi 4)"}},
+		},
+		{
+			name: "default hostname with a randomized MAC",
+			dev:  Device{MAC: "06:00:00:00:00:01", Category: "Randomized MAC", Hostname: "raspberrypi.lan"},
+			want: Device{IsRaspberryPi: true, Category: "Raspberry Pi", OS: "Raspberry Pi OS", Hostname: "raspberrypi.lan",
+				Fingerprint: []string{"hostname raspberrypi.lan"}},
+		},
+		{
+			name: "model in the hostname beats the OUI",
+			dev:  newDevice("10.0.0.3", "b8:27:eb:00:00:01", AddressFamilyIPv4),
+			mdns: mdnsRecords{Hostnames: []string{"rpi-zero2w.local"}},
+			want: Device{IsRaspberryPi: true, Category: "Raspberry Pi", PiModel: "Raspberry Pi Zero 2 W",
+				Hostname: "rpi-zero2w.local", HostnameSource: HostnameMDNS,
+				Fingerprint: []string{"oui b8:27:eb (Raspberry Pi 1/2/3/Zero)", "hostname rpi-zero2w.local"}},
+		},
+		{
+			name: "cluster node number",
+			dev:  Device{MAC: "00:11:22:33:44:55", Category: "Unknown", Hostname: "pi-3"},
+			want: Device{Category: "Unknown", Hostname: "pi-3"},
+		},
+		{
+			name:   "announced model and Raspbian banner",
+			dev:    Device{MAC: "00:11:22:33:44:55", Category: "Unknown", Hostname: "pi-3"},
+			mdns:   mdnsRecords{TXT: []string{"model=Raspberry Pi 3 Model B Rev 1.2", "os=Raspbian"}},
+			banner: "SSH-2.0-OpenSSH_7.4p1 Raspbian-10+deb9u7",
+			want: Device{IsRaspberryPi: true, Category: "Raspberry Pi", PiModel: "Raspberry Pi 3 Model B Rev 1.2",
+				OS: "Raspbian (Debian 9 stretch)", Hostname: "pi-3",
+				Fingerprint: []string{"mdns model=Raspberry Pi 3 Model B Rev 1.2", "mdns os=Raspbian", "ssh SSH-2.0-OpenSSH_7.4p1 Raspbian-10+deb9u7"}},
+		},
+		{
+			name: "workstation service carrying a Pi MAC",
+			dev:  Device{MAC: "06:00:00:00:00:02", Category: "Randomized MAC", Hostname: "kitchen"},
+			mdns: mdnsRecords{Instances: []string{"kitchen [e4:5f:01:aa:bb:cc]._workstation._tcp.local"}},
+			want: Device{IsRaspberryPi: true, Category: "Raspberry Pi", PiModel: "Raspberry Pi 4/400/Zero 2 W", Hostname: "kitchen",
+				Fingerprint: []string{"mdns workstation MAC e4:5f:01:aa:bb:cc"}},
+		},
+		{
+			name:   "Debian server",
+			dev:    Device{MAC: "00:11:22:33:44:55", Category: "Unknown", Hostname: "nas"},
+			banner: "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3",
+			want: Device{Category: "Unknown", OS: "Debian 12 (bookworm)", Hostname: "nas",
+				Fingerprint: []string{"ssh SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3"}},
+		},
+	}
+	for _, tt := range tests {
+		dev := tt.dev
+		applyFingerprint(&dev, tt.mdns, tt.banner)
+		got := Device{
+			IsRaspberryPi:  dev.IsRaspberryPi,
+			Category:       dev.Category,
+			PiModel:        dev.PiModel,
+			OS:             dev.OS,
+			Hostname:       dev.Hostname,
+			HostnameSource: dev.HostnameSource,
+			Fingerprint:    dev.Fingerprint,
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
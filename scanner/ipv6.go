@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// Address families reported in Device.AddressFamily
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+)
+
+// allNodesMulticast is the IPv6 link-local all-nodes group every host listens on
+var allNodesMulticast = net.ParseIP("ff02::1")
+
+// discoverIPv6 pings the all-nodes multicast group on the given interface and returns
+// the link-local addresses (with zone) of every host that answered
+func discoverIPv6(ctx context.Context, ifaceName string, timeout time.Duration, count int) ([]string, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown interface %q: %w", ifaceName, err)
+	}
+
+	// Prefer unprivileged ping sockets, falling back to raw ICMPv6 when running as root
+	var dst net.Addr = &net.UDPAddr{IP: allNodesMulticast, Zone: iface.Name}
+	conn, err := icmp.ListenPacket("udp6", "::")
+	if err != nil {
+		dst = &net.IPAddr{IP: allNodesMulticast, Zone: iface.Name}
+		conn, err = icmp.ListenPacket("ip6:ipv6-icmp", "::")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ICMPv6 socket: %w", err)
+		}
+	}
+	defer conn.Close()
+
+	// Make sure the multicast echo leaves through the chosen interface
+	if err := conn.IPv6PacketConn().SetMulticastInterface(iface); err != nil {
+		return nil, fmt.Errorf("failed to select interface %s: %w", iface.Name, err)
+	}
+
+	for seq := 1; seq <= max(count, 1); seq++ {
+		msg := icmp.Message{
+			Type: ipv6.ICMPTypeEchoRequest,
+			Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: []byte("gofindpi")},
+		}
+		wb, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build echo request: %w", err)
+		}
+		if _, err := conn.WriteTo(wb, dst); err != nil {
+			return nil, fmt.Errorf("failed to send to %s%%%s: %w", allNodesMulticast, iface.Name, err)
+		}
+	}
+
+	// Every host answers the multicast echo, so keep reading until the timeout expires
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	localAddrs := make(map[string]bool)
+	if addrs, err := iface.Addrs(); err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				localAddrs[ipnet.IP.String()] = true
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var found []string
+	rb := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(rb)
+		if err != nil {
+			break // deadline reached
+		}
+		reply, err := icmp.ParseMessage(ipv6.ICMPTypeEchoReply.Protocol(), rb[:n])
+		if err != nil || reply.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+
+		var ip net.IP
+		switch a := peer.(type) {
+		case *net.UDPAddr:
+			ip = a.IP
+		case *net.IPAddr:
+			ip = a.IP
+		}
+		if ip == nil || localAddrs[ip.String()] {
+			continue
+		}
+
+		addr := ip.String()
+		if ip.IsLinkLocalUnicast() {
+			addr += "%" + iface.Name
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			found = append(found, addr)
+		}
+	}
+
+	return found, nil
+}
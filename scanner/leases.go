@@ -0,0 +1,402 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/james-see/gofindpi/data"
+)
+
+// DiscoveredByLease is reported in Device.DiscoveredBy for known offline devices,
+// which only a DHCP lease vouches for
+const DiscoveredByLease = "dhcp-lease"
+
+// Lease is an IPv4 address a DHCP server handed out, as recorded in its lease file
+type Lease struct {
+	IP          string    `json:"ip"`
+	MAC         string    `json:"mac"`
+	Hostname    string    `json:"hostname,omitempty"`     // Name the client asked for
+	ClientID    string    `json:"client_id,omitempty"`    // Option 61, as colon-separated hex
+	VendorClass string    `json:"vendor_class,omitempty"` // Option 60, e.g. "MSFT 5.0"
+	Expires     time.Time `json:"expires,omitzero"`       // Zero for leases that never expire
+}
+
+// active reports whether the lease is still valid at t
+func (l Lease) active(t time.Time) bool {
+	return l.Expires.IsZero() || l.Expires.After(t)
+}
+
+// ReadLeaseFile reads a dnsmasq, ISC dhcpd or Kea lease file
+func ReadLeaseFile(path string) ([]Lease, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	leases, err := ParseLeases(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return leases, nil
+}
+
+// ParseLeases reads leases in dnsmasq, ISC dhcpd or Kea CSV format, detected from the
+// start of the file
+func ParseLeases(r io.Reader) ([]Lease, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(64)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	start := strings.TrimSpace(string(first))
+	switch {
+	case strings.HasPrefix(start, "address,"):
+		return ParseKeaLeases(br)
+	case start == "", start[0] >= '0' && start[0] <= '9', strings.HasPrefix(start, "duid "):
+		return ParseDnsmasqLeases(br)
+	}
+	return ParseISCLeases(br)
+}
+
+// ParseDnsmasqLeases reads a dnsmasq lease file (dnsmasq.leases), one lease per line:
+//
+//	1704164645 b8:27:eb:12:34:56 192.168.1.20 raspberrypi 01:b8:27:eb:12:34:56
+//
+// The expiry is a Unix time, 0 for infinite leases, and "*" marks an unknown hostname
+// or client ID. DHCPv6 leases, which carry no MAC address, are skipped.
+func ParseDnsmasqLeases(r io.Reader) ([]Lease, error) {
+	var leases []Lease
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) < 4 || fields[0] == "duid" {
+			continue
+		}
+		mac, err := data.NormalizeMAC(fields[1])
+		if err != nil {
+			continue
+		}
+		lease := Lease{IP: fields[2], MAC: mac}
+		if !validLeaseIP(lease.IP) {
+			continue
+		}
+		if expiry, err := strconv.ParseInt(fields[0], 10, 64); err == nil && expiry > 0 {
+			lease.Expires = time.Unix(expiry, 0)
+		}
+		if fields[3] != "*" {
+			lease.Hostname = fields[3]
+		}
+		if len(fields) > 4 && fields[4] != "*" {
+			lease.ClientID = fields[4]
+		}
+		leases = append(leases, lease)
+	}
+	return leases, lines.Err()
+}
+
+// ParseISCLeases reads an ISC dhcpd lease file (dhcpd.leases):
+//
+//	lease 192.168.1.20 {
+//	  starts 2 2024/01/02 01:04:05;
+//	  ends 2 2024/01/02 03:04:05;
+//	  binding state active;
+//	  hardware ethernet b8:27:eb:12:34:56;
+//	  uid "\001\270'\353\0224V";
+//	  set vendor-class-identifier = "MSFT 5.0";
+//	  client-hostname "raspberrypi";
+//	}
+//
+// dhcpd appends a new record whenever a lease changes, so the last record for an
+// address wins. Leases that are not active (free, released, abandoned) are skipped.
+func ParseISCLeases(r io.Reader) ([]Lease, error) {
+	var (
+		order   []string // Addresses in the order first seen
+		byIP    = make(map[string]Lease)
+		current *Lease
+		state   string
+	)
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if current == nil {
+			if ip, ok := strings.CutPrefix(line, "lease "); ok && strings.HasSuffix(ip, "{") {
+				current = &Lease{IP: strings.TrimSpace(strings.TrimSuffix(ip, "{"))}
+				state = "active"
+			}
+			continue
+		}
+
+		if line == "}" {
+			if current.MAC != "" && validLeaseIP(current.IP) && state == "active" {
+				if _, seen := byIP[current.IP]; !seen {
+					order = append(order, current.IP)
+				}
+				byIP[current.IP] = *current
+			} else {
+				delete(byIP, current.IP) // A later record freed the address
+			}
+			current = nil
+			continue
+		}
+
+		statement := strings.TrimSuffix(line, ";")
+		keyword, value, _ := strings.Cut(statement, " ")
+		switch keyword {
+		case "ends":
+			current.Expires = parseISCTime(value)
+		case "binding":
+			state = strings.TrimPrefix(value, "state ")
+		case "hardware":
+			if hw, ok := strings.CutPrefix(value, "ethernet "); ok {
+				current.MAC, _ = data.NormalizeMAC(hw)
+			}
+		case "uid":
+			current.ClientID = parseISCClientID(value)
+		case "client-hostname":
+			current.Hostname = unquoteISC(value)
+		case "set":
+			if v, ok := strings.CutPrefix(value, "vendor-class-identifier = "); ok {
+				current.VendorClass = unquoteISC(v)
+			}
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	var leases []Lease
+	for _, ip := range order {
+		if lease, ok := byIP[ip]; ok {
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
+
+// parseISCTime parses the time of an ISC lease statement, either "never", the
+// default "2 2024/01/02 03:04:05" in UTC (weekday first) or "epoch 1704164645; # ..."
+// written with db-time-format local
+func parseISCTime(value string) time.Time {
+	fields := strings.Fields(value)
+	switch {
+	case len(fields) >= 2 && fields[0] == "epoch":
+		if secs, err := strconv.ParseInt(strings.TrimSuffix(fields[1], ";"), 10, 64); err == nil {
+			return time.Unix(secs, 0)
+		}
+	case len(fields) >= 3:
+		if t, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2]); err == nil {
+			return t
+		}
+	}
+	return time.Time{} // "never", or unreadable
+}
+
+// parseISCClientID converts a uid statement, either a quoted string with octal
+// escapes or colon-separated hex, into colon-separated hex
+func parseISCClientID(value string) string {
+	if !strings.HasPrefix(value, `"`) {
+		return strings.ToLower(value)
+	}
+	raw := unquoteISC(value)
+	hex := make([]string, len(raw))
+	for i := 0; i < len(raw); i++ {
+		hex[i] = fmt.Sprintf("%02x", raw[i])
+	}
+	return strings.Join(hex, ":")
+}
+
+// unquoteISC removes the quotes from a dhcpd string, decoding \" \\ and octal
+// escapes such as \001
+func unquoteISC(value string) string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i+1 == len(value) {
+			b.WriteByte(c)
+			continue
+		}
+		if i+3 < len(value) && isOctal(value[i+1]) && isOctal(value[i+2]) && isOctal(value[i+3]) {
+			n, _ := strconv.ParseUint(value[i+1:i+4], 8, 8)
+			b.WriteByte(byte(n))
+			i += 3
+			continue
+		}
+		b.WriteByte(value[i+1])
+		i++
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool { return c >= '0' && c <= '7' }
+
+// ParseKeaLeases reads a Kea DHCPv4 memfile lease file (kea-leases4.csv):
+//
+//	address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context,pool_id
+//	192.168.1.20,b8:27:eb:12:34:56,01:b8:27:eb:12:34:56,3600,1704164645,1,0,0,raspberrypi,0,,0
+//
+// Columns are found by name, as their number varies between Kea versions. Kea appends
+// a row whenever a lease changes, so the last row for an address wins, and only
+// leases in the default state 0 are kept.
+func ParseKeaLeases(r io.Reader) ([]Lease, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	column := make(map[string]int)
+	for i, name := range header {
+		column[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"address", "hwaddr"} {
+		if _, ok := column[required]; !ok {
+			return nil, fmt.Errorf("lease file has no %q column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := column[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var (
+		order []string
+		byIP  = make(map[string]Lease)
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ip := field(record, "address")
+		mac, err := data.NormalizeMAC(field(record, "hwaddr"))
+		if err != nil || !validLeaseIP(ip) {
+			continue
+		}
+		if state := field(record, "state"); state != "" && state != "0" {
+			delete(byIP, ip) // Declined or reclaimed
+			continue
+		}
+
+		lease := Lease{
+			IP:       ip,
+			MAC:      mac,
+			Hostname: strings.TrimSuffix(field(record, "hostname"), "."),
+			ClientID: field(record, "client_id"),
+		}
+		// valid_lifetime 0xffffffff marks an infinite lease
+		if field(record, "valid_lifetime") != "4294967295" {
+			if expiry, err := strconv.ParseInt(field(record, "expire"), 10, 64); err == nil && expiry > 0 {
+				lease.Expires = time.Unix(expiry, 0)
+			}
+		}
+		if _, seen := byIP[ip]; !seen {
+			order = append(order, ip)
+		}
+		byIP[ip] = lease
+	}
+
+	var leases []Lease
+	for _, ip := range order {
+		if lease, ok := byIP[ip]; ok {
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
+
+// validLeaseIP reports whether a lease address is a plain IPv4 address
+func validLeaseIP(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && addr.Is4()
+}
+
+// leaseFor picks a device's lease: the one for its current address if there is one,
+// otherwise the one expiring last
+func leaseFor(leases []Lease, ip string) (Lease, bool) {
+	var best Lease
+	found := false
+	for _, l := range leases {
+		if l.IP == ip {
+			return l, true
+		}
+		if !found || expiresLater(l, best) {
+			best, found = l, true
+		}
+	}
+	return best, found
+}
+
+// expiresLater reports whether lease a outlasts lease b; infinite leases outlast all
+func expiresLater(a, b Lease) bool {
+	return !b.Expires.IsZero() && (a.Expires.IsZero() || a.Expires.After(b.Expires))
+}
+
+// attachLeases copies the lease of each device onto it, matched by MAC address. The
+// lease hostname is used when no resolver found a name.
+func attachLeases(devices []Device, byMAC map[string][]Lease) {
+	for i := range devices {
+		dev := &devices[i]
+		lease, ok := leaseFor(byMAC[dev.MAC], dev.IP)
+		if !ok {
+			continue
+		}
+		dev.Lease = &lease
+		if dev.Hostname == "" && lease.Hostname != "" {
+			dev.Hostname, dev.HostnameSource = lease.Hostname, HostnameDHCP
+		}
+	}
+}
+
+// knownOffline builds devices for active leases on scanned addresses whose holders
+// did not answer
+func (s *Scanner) knownOffline(targets []string, found []Device) []Device {
+	scanned := make(map[string]bool, len(targets))
+	for _, ip := range targets {
+		scanned[ip] = true
+	}
+	seen := make(map[string]bool)
+	for _, dev := range found {
+		seen[dev.MAC], seen[dev.IP] = true, true
+	}
+
+	now := time.Now()
+	var offline []Device
+	for _, lease := range s.leases {
+		if !scanned[lease.IP] || seen[lease.MAC] || seen[lease.IP] || !lease.active(now) {
+			continue
+		}
+		seen[lease.MAC] = true
+
+		dev := newDevice(lease.IP, lease.MAC, AddressFamilyIPv4)
+		dev.DiscoveredBy = DiscoveredByLease
+		dev.Evidence = "leased, did not answer"
+		if lease.Hostname != "" {
+			dev.Hostname, dev.HostnameSource = lease.Hostname, HostnameDHCP
+		}
+		dev.Lease = &lease
+		offline = append(offline, dev)
+	}
+	detectFamilies(s.families, offline)
+	sortDevices(offline)
+	return offline
+}
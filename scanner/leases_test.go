@@ -0,0 +1,176 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeaseParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		parse   func(io.Reader) ([]Lease, error)
+		want    []Lease
+	}{
+		{
+			name:    "dnsmasq",
+			fixture: "dnsmasq.leases",
+			parse:   ParseDnsmasqLeases,
+			want: []Lease{
+				{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", Hostname: "raspberrypi", ClientID: "01:b8:27:eb:12:34:56", Expires: time.Unix(1704164645, 0)},
+				{IP: "192.168.1.21", MAC: "dc:a6:32:ab:cd:ef"}, // Infinite, no name
+				{IP: "192.168.1.22", MAC: "3c:22:fb:01:02:03", Hostname: "old-laptop", Expires: time.Unix(1577836800, 0)},
+			},
+		},
+		{
+			// Free, released and abandoned bindings are dropped, escapes decoded
+			name:    "ISC dhcpd",
+			fixture: "dhcpd.leases",
+			parse:   ParseISCLeases,
+			want: []Lease{
+				{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", Hostname: "raspberrypi", ClientID: "01:b8:27:eb:12:34:56",
+					VendorClass: "MSFT 5.0", Expires: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				{IP: "192.168.1.21", MAC: "dc:a6:32:ab:cd:ef", Hostname: "café-pi", ClientID: "01:dc:a6:32:ab:cd:ef"},
+				{IP: "192.168.1.24", MAC: "e4:5f:01:aa:bb:cc", Hostname: `bob"s \pi`, Expires: time.Unix(1704164645, 0)},
+			},
+		},
+		{
+			// Declined and reclaimed rows are dropped, the last row of a renewal wins
+			name:    "Kea CSV",
+			fixture: "kea-leases4.csv",
+			parse:   ParseKeaLeases,
+			want: []Lease{
+				{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", Hostname: "raspberrypi.lan", ClientID: "01:b8:27:eb:12:34:56", Expires: time.Unix(1704164645, 0)},
+				{IP: "192.168.1.21", MAC: "dc:a6:32:ab:cd:ef"},
+				{IP: "192.168.1.24", MAC: "e4:5f:01:aa:bb:cc", Hostname: "pi4", Expires: time.Unix(1704180000, 0)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := tt.parse(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+
+			// The format is detected from the start of the file
+			detected, err := ReadLeaseFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(detected, tt.want) {
+				t.Errorf("ReadLeaseFile got %+v\nwant %+v", detected, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeaLeasesColumns(t *testing.T) {
+	// Kea 1.x files have no state column and fewer columns in all
+	old := "address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname\n" +
+		"192.168.1.20,b8:27:eb:12:34:56,,3600,1704164645,1,0,0,raspberrypi\n"
+	got, err := ParseKeaLeases(strings.NewReader(old))
+	want := []Lease{{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", Hostname: "raspberrypi", Expires: time.Unix(1704164645, 0)}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("without a state column: %+v, %v", got, err)
+	}
+
+	if _, err := ParseKeaLeases(strings.NewReader("address,client_id\n192.168.1.20,01\n")); err == nil || !strings.Contains(err.Error(), `no "hwaddr" column`) {
+		t.Errorf("without a hwaddr column: %v", err)
+	}
+	if _, err := ParseKeaLeases(strings.NewReader("")); err == nil {
+		t.Error("an empty file parsed as Kea CSV")
+	}
+}
+
+func TestParseLeasesEmpty(t *testing.T) {
+	leases, err := ParseLeases(strings.NewReader("\n"))
+	if err != nil || len(leases) != 0 {
+		t.Errorf("ParseLeases(empty) = %+v, %v", leases, err)
+	}
+}
+
+func TestParseISCTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2 2024/01/02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"epoch 1704164645; # Tue Jan 02 03:04:05 2024", time.Unix(1704164645, 0)},
+		{"never", time.Time{}},
+		{"2 2024/13/02 03:04:05", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseISCTime(tt.value); !got.Equal(tt.want) {
+			t.Errorf("parseISCTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUnquoteISC(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{`"raspberrypi"`, "raspberrypi"},
+		{`"caf\303\251"`, "café"},
+		{`"a\"b"`, `a"b`},
+		{`"a\\b"`, `a\b`},
+		{`"\001\270'"`, "\x01\xb8'"},
+		{`"trailing\"`, `trailing\`},
+		{`"\12"`, "12"}, // Octal escapes are always three digits
+	}
+	for _, tt := range tests {
+		if got := unquoteISC(tt.value); got != tt.want {
+			t.Errorf("unquoteISC(%s) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestKnownOffline(t *testing.T) {
+	now := time.Now()
+	s := New(WithLeases(
+		Lease{IP: "192.168.1.20", MAC: "b8:27:eb:12:34:56", Hostname: "raspberrypi", Expires: now.Add(time.Hour)},
+		Lease{IP: "192.168.1.21", MAC: "dc:a6:32:ab:cd:ef"},                                                 // Infinite
+		Lease{IP: "192.168.1.22", MAC: "3c:22:fb:01:02:03", Expires: now.Add(-time.Hour)},                   // Expired
+		Lease{IP: "192.168.1.23", MAC: "00:1b:02:3c:04:05", Expires: now.Add(time.Hour)},                    // Answered
+		Lease{IP: "192.168.1.24", MAC: "e4:5f:01:aa:bb:cc", Expires: now.Add(time.Hour)},                    // Answered at another address
+		Lease{IP: "10.0.0.1", MAC: "b8:27:eb:00:00:01", Expires: now.Add(time.Hour)},                        // Not scanned
+		Lease{IP: "192.168.1.25", MAC: "b8:27:eb:12:34:56", Hostname: "again", Expires: now.Add(time.Hour)}, // Listed once
+	), WithKnownOffline(true))
+
+	targets := []string{"192.168.1.20", "192.168.1.21", "192.168.1.22", "192.168.1.23", "192.168.1.24", "192.168.1.25"}
+	found := []Device{
+		newDevice("192.168.1.23", "00:1b:02:3c:04:05", AddressFamilyIPv4),
+		newDevice("192.168.1.30", "e4:5f:01:aa:bb:cc", AddressFamilyIPv4),
+	}
+	offline := s.knownOffline(targets, found)
+
+	var ips []string
+	for _, dev := range offline {
+		ips = append(ips, dev.IP)
+		if dev.DiscoveredBy != DiscoveredByLease || dev.Lease == nil || dev.Lease.IP != dev.IP {
+			t.Errorf("offline device %+v", dev)
+		}
+	}
+	if want := []string{"192.168.1.20", "192.168.1.21"}; !reflect.DeepEqual(ips, want) {
+		t.Fatalf("offline %v, want %v", ips, want)
+	}
+	if pi := offline[0]; pi.Hostname != "raspberrypi" || pi.HostnameSource != HostnameDHCP || !pi.IsRaspberryPi {
+		t.Errorf("leased Pi %+v", pi)
+	}
+}
@@ -0,0 +1,167 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsPort is the multicast DNS port. A query sent there from any other port is a
+// legacy unicast query (RFC 6762 section 6.7), which responders answer directly.
+const mdnsPort = 5353
+
+// Services queried when fingerprinting a host. Avahi advertises both by default on
+// Raspberry Pi OS; the workstation instance name includes the interface MAC.
+const (
+	serviceWorkstation = "_workstation._tcp.local."
+	serviceSSH         = "_ssh._tcp.local."
+)
+
+// mdnsRecords is what a responder said about itself
+type mdnsRecords struct {
+	Hostnames []string // From the reverse lookup and SRV targets, without the trailing dot
+	Instances []string // Service instance names, e.g. "raspberrypi [b8:27:eb:12:34:56]._workstation._tcp.local"
+	TXT       []string // key=value strings
+}
+
+// queryMDNS asks the host at ip for its name and the workstation and SSH services it
+// advertises. It returns after the first answer or when timeout expires.
+func queryMDNS(ctx context.Context, ip string, timeout time.Duration) (mdnsRecords, error) {
+	reverse, err := reverseName(ip)
+	if err != nil {
+		return mdnsRecords{}, err
+	}
+
+	id := uint16(rand.N(1 << 16))
+	query, err := buildMDNSQuery(id, []dnsmessage.Question{
+		ptrQuestion(reverse),
+		ptrQuestion(serviceWorkstation),
+		ptrQuestion(serviceSSH),
+	})
+	if err != nil {
+		return mdnsRecords{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(ip, strconv.Itoa(mdnsPort)))
+	if err != nil {
+		return mdnsRecords{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return mdnsRecords{}, err
+	}
+
+	buf := make([]byte, 9000) // mDNS allows jumbo-sized packets
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return mdnsRecords{}, err
+		}
+		var msg dnsmessage.Message
+		if err := msg.Unpack(buf[:n]); err != nil || msg.ID != id || !msg.Response {
+			continue
+		}
+		return parseMDNSResponse(msg, reverse), nil
+	}
+}
+
+// buildMDNSQuery packs a query message
+func buildMDNSQuery(id uint16, questions []dnsmessage.Question) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, q := range questions {
+		if err := b.Question(q); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// ptrQuestion asks for the PTR records of a fully-qualified name
+func ptrQuestion(name string) dnsmessage.Question {
+	return dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET,
+	}
+}
+
+// parseMDNSResponse collects the names and TXT strings from a response to queryMDNS
+func parseMDNSResponse(msg dnsmessage.Message, reverse string) mdnsRecords {
+	var records mdnsRecords
+	for _, rr := range append(msg.Answers, msg.Additionals...) {
+		owner := rr.Header.Name.String()
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			target := strings.TrimSuffix(body.PTR.String(), ".")
+			if strings.EqualFold(owner, reverse) {
+				records.Hostnames = appendUnique(records.Hostnames, target)
+			} else {
+				records.Instances = appendUnique(records.Instances, target)
+			}
+		case *dnsmessage.SRVResource:
+			records.Hostnames = appendUnique(records.Hostnames, strings.TrimSuffix(body.Target.String(), "."))
+		case *dnsmessage.TXTResource:
+			for _, txt := range body.TXT {
+				if txt != "" {
+					records.TXT = appendUnique(records.TXT, txt)
+				}
+			}
+		}
+	}
+	return records
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name for an address
+func reverseName(ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	addr = addr.WithZone("").Unmap()
+
+	var b strings.Builder
+	raw := addr.AsSlice()
+	if addr.Is4() {
+		for i := len(raw) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "%d.", raw[i])
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String(), nil
+	}
+	const hexDigits = "0123456789abcdef"
+	for i := len(raw) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[raw[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[raw[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
+
+// appendUnique appends s unless it is already present
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
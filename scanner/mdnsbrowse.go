@@ -0,0 +1,286 @@
+package scanner
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+// serviceEnumeration lists every service type on the link (RFC 6763 section 9)
+const serviceEnumeration = "_services._dns-sd._udp.local."
+
+// mdnsMinWindow is the shortest time the browser listens for answers. Responders
+// delay their replies by up to half a second to avoid collisions.
+const mdnsMinWindow = 1500 * time.Millisecond
+
+// mDNS multicast groups
+var (
+	mdnsGroupIPv4 = netip.MustParseAddr("224.0.0.251")
+	mdnsGroupIPv6 = netip.MustParseAddr("ff02::fb")
+)
+
+// Service is a DNS-SD service advertised by a device
+type Service struct {
+	Instance string   `json:"instance"` // e.g. "raspberrypi [b8:27:eb:12:34:56]"
+	Type     string   `json:"type"`     // e.g. "_workstation._tcp"
+	Port     int      `json:"port,omitempty"`
+	TXT      []string `json:"txt,omitempty"`
+}
+
+// mdnsHost is what the browser learned about one address
+type mdnsHost struct {
+	Name     string // e.g. "raspberrypi.local"
+	Services []Service
+}
+
+// mdnsBrowser enumerates DNS-SD services by sending one-shot multicast queries from
+// an ephemeral port, which responders answer by unicast (RFC 6762 section 5.1), so
+// it works alongside a system responder that owns port 5353
+type mdnsBrowser struct {
+	mu sync.Mutex
+
+	// Names are keyed in lower case, as DNS names compare case-insensitively
+	types     map[string]bool         // Service types, e.g. "_ssh._tcp.local."
+	instances map[string]instanceInfo // By instance name
+	srv       map[string]srvInfo      // By instance name
+	txt       map[string][]string     // By instance name
+	addrs     map[string][]string     // Host name -> addresses
+}
+
+// instanceInfo is a service instance seen in a PTR answer
+type instanceInfo struct {
+	Name   string // As announced, e.g. "Living Room._airplay._tcp.local."
+	Type   string
+	Sender string // Address of the responder that announced it
+}
+
+type srvInfo struct {
+	Target string
+	Port   int
+}
+
+// mdnsConn is a socket the browser queries through, with the group to send to
+type mdnsConn struct {
+	conn  net.PacketConn
+	group net.Addr
+}
+
+func newMDNSBrowser() *mdnsBrowser {
+	return &mdnsBrowser{
+		types:     make(map[string]bool),
+		instances: make(map[string]instanceInfo),
+		srv:       make(map[string]srvInfo),
+		txt:       make(map[string][]string),
+		addrs:     make(map[string][]string),
+	}
+}
+
+// openMDNSConns opens an IPv4 socket sending to 224.0.0.251 on the named interface
+// (or the system default) and, if ipv6Iface is set, an IPv6 socket sending to ff02::fb
+// on that interface
+func openMDNSConns(ifaceName, ipv6Iface string) ([]mdnsConn, error) {
+	var conns []mdnsConn
+
+	conn4, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	if ifaceName != "" {
+		if ifi, err := net.InterfaceByName(ifaceName); err == nil {
+			ipv4.NewPacketConn(conn4).SetMulticastInterface(ifi)
+		}
+	}
+	conns = append(conns, mdnsConn{conn4, &net.UDPAddr{IP: mdnsGroupIPv4.AsSlice(), Port: mdnsPort}})
+
+	if ipv6Iface != "" {
+		conn6, err := net.ListenPacket("udp6", ":0")
+		if err == nil {
+			conns = append(conns, mdnsConn{conn6, &net.UDPAddr{IP: mdnsGroupIPv6.AsSlice(), Port: mdnsPort, Zone: ipv6Iface}})
+		}
+	}
+	return conns, nil
+}
+
+// browse enumerates service types, then the instances of each type, collecting
+// every record in the answers until ctx is done. It closes the connections.
+func (b *mdnsBrowser) browse(ctx context.Context, conns []mdnsConn) {
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func(c mdnsConn) {
+			defer wg.Done()
+			b.listen(c)
+		}(c)
+		b.query(c, serviceEnumeration)
+	}
+
+	<-ctx.Done()
+	for _, c := range conns {
+		c.conn.Close() // Unblocks the listeners
+	}
+	wg.Wait()
+}
+
+// query sends a PTR query for name to the multicast group
+func (b *mdnsBrowser) query(c mdnsConn, name string) {
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		return
+	}
+	msg, err := buildMDNSQuery(0, []dnsmessage.Question{{Name: n, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}})
+	if err != nil {
+		return
+	}
+	c.conn.WriteTo(msg, c.group)
+}
+
+// listen reads responses until the connection is closed
+func (b *mdnsBrowser) listen(c mdnsConn) {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var msg dnsmessage.Message
+		if err := msg.Unpack(buf[:n]); err != nil || !msg.Response {
+			continue
+		}
+		sender := ""
+		if udp, ok := from.(*net.UDPAddr); ok {
+			if addr, ok := netip.AddrFromSlice(udp.IP); ok {
+				sender = addr.Unmap().String()
+			}
+		}
+		for _, newType := range b.record(msg, sender) {
+			b.query(c, newType)
+		}
+	}
+}
+
+// record stores the records of a response and returns service types not seen before
+func (b *mdnsBrowser) record(msg dnsmessage.Message, sender string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var newTypes []string
+	for _, rr := range append(msg.Answers, msg.Additionals...) {
+		owner := strings.ToLower(rr.Header.Name.String())
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			target := body.PTR.String()
+			if owner == serviceEnumeration {
+				if t := strings.ToLower(target); !b.types[t] {
+					b.types[t] = true
+					newTypes = append(newTypes, t)
+				}
+				continue
+			}
+			if strings.HasSuffix(owner, ".in-addr.arpa.") || strings.HasSuffix(owner, ".ip6.arpa.") {
+				continue
+			}
+			b.instances[strings.ToLower(target)] = instanceInfo{Name: target, Type: owner, Sender: sender}
+		case *dnsmessage.SRVResource:
+			b.srv[owner] = srvInfo{Target: strings.ToLower(body.Target.String()), Port: int(body.Port)}
+		case *dnsmessage.TXTResource:
+			var txt []string
+			for _, s := range body.TXT {
+				if s != "" {
+					txt = append(txt, s)
+				}
+			}
+			b.txt[owner] = txt
+		case *dnsmessage.AResource:
+			b.addrs[owner] = appendUnique(b.addrs[owner], netip.AddrFrom4(body.A).String())
+		case *dnsmessage.AAAAResource:
+			b.addrs[owner] = appendUnique(b.addrs[owner], netip.AddrFrom16(body.AAAA).Unmap().String())
+		}
+	}
+	return newTypes
+}
+
+// hosts assembles the collected records by address. Services are attached to the
+// addresses of their SRV target, or to the responder's address when the target's
+// addresses were not announced.
+func (b *mdnsBrowser) hosts() map[string]mdnsHost {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make(map[string]mdnsHost)
+	attach := func(addrs []string, name string, svc *Service) {
+		for _, addr := range addrs {
+			h := result[addr]
+			if h.Name == "" {
+				h.Name = strings.TrimSuffix(name, ".")
+			}
+			if svc != nil {
+				h.Services = append(h.Services, *svc)
+			}
+			result[addr] = h
+		}
+	}
+
+	// Sorted, so the name kept for an address with several is deterministic
+	for _, host := range slices.Sorted(maps.Keys(b.addrs)) {
+		attach(b.addrs[host], host, nil)
+	}
+	for _, key := range slices.Sorted(maps.Keys(b.instances)) {
+		inst := b.instances[key]
+		svc := Service{
+			Instance: inst.Name[:max(len(inst.Name)-len(inst.Type)-1, 0)],
+			Type:     strings.TrimSuffix(inst.Type, ".local."),
+			TXT:      b.txt[key],
+		}
+		srv, ok := b.srv[key]
+		svc.Port = srv.Port
+		addrs := b.addrs[srv.Target]
+		if !ok || len(addrs) == 0 {
+			if inst.Sender == "" {
+				continue
+			}
+			addrs = []string{inst.Sender}
+		}
+		attach(addrs, srv.Target, &svc)
+	}
+	return result
+}
+
+// browseMDNS listens for DNS-SD announcements until ctx is done and returns what
+// was learned, keyed by address without zone
+func browseMDNS(ctx context.Context, ifaceName, ipv6Iface string) (map[string]mdnsHost, error) {
+	conns, err := openMDNSConns(ifaceName, ipv6Iface)
+	if err != nil {
+		return nil, err
+	}
+	b := newMDNSBrowser()
+	b.browse(ctx, conns)
+	return b.hosts(), nil
+}
+
+// attachMDNS copies browse results onto the matching devices
+func attachMDNS(devices []Device, hosts map[string]mdnsHost) {
+	for i := range devices {
+		dev := &devices[i]
+		ip, _, _ := strings.Cut(dev.IP, "%")
+		h, ok := hosts[ip]
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(h.Name, ".local") {
+			dev.MDNSName = h.Name
+		}
+		// Services found through the responder's address come without a name
+		if dev.Hostname == "" && h.Name != "" {
+			dev.Hostname, dev.HostnameSource = h.Name, HostnameMDNS
+		}
+		dev.Services = h.Services
+	}
+}
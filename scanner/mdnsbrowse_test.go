@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsAnswer builds a resource record for a test response
+func mdnsAnswer(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: 120},
+		Body:   body,
+	}
+}
+
+func mdnsResponse(answers, additionals []dnsmessage.Resource) dnsmessage.Message {
+	return dnsmessage.Message{
+		Header:      dnsmessage.Header{Response: true, Authoritative: true},
+		Answers:     answers,
+		Additionals: additionals,
+	}
+}
+
+// fakeResponder answers the browser's PTR queries from a fixed table, standing in
+// for the multicast group
+func fakeResponder(t *testing.T, answers map[string]dnsmessage.Message) net.Addr {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			resp, ok := answers[query.Questions[0].Name.String()]
+			if !ok {
+				continue
+			}
+			packed, err := resp.Pack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.WriteTo(packed, from)
+		}
+	}()
+	return conn.LocalAddr()
+}
+
+func TestMDNSBrowse(t *testing.T) {
+	group := fakeResponder(t, map[string]dnsmessage.Message{
+		serviceEnumeration: mdnsResponse([]dnsmessage.Resource{
+			mdnsAnswer(serviceEnumeration, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_ssh._tcp.local.")}),
+			mdnsAnswer(serviceEnumeration, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_ipp._tcp.local.")}),
+		}, nil),
+		"_ssh._tcp.local.": mdnsResponse(
+			[]dnsmessage.Resource{
+				mdnsAnswer("_ssh._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("raspberrypi._ssh._tcp.local.")}),
+			},
+			[]dnsmessage.Resource{
+				mdnsAnswer("raspberrypi._ssh._tcp.local.", &dnsmessage.SRVResource{Target: dnsmessage.MustNewName("RaspberryPi.local."), Port: 22}),
+				mdnsAnswer("raspberrypi._ssh._tcp.local.", &dnsmessage.TXTResource{TXT: []string{""}}),
+				mdnsAnswer("raspberrypi.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 50}}),
+			},
+		),
+		// No SRV record, so the service is attached to the responder's address
+		"_ipp._tcp.local.": mdnsResponse([]dnsmessage.Resource{
+			mdnsAnswer("_ipp._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Office Printer._ipp._tcp.local.")}),
+			mdnsAnswer("Office Printer._ipp._tcp.local.", &dnsmessage.TXTResource{TXT: []string{"rp=ipp/print", "ty=Example"}}),
+		}, nil),
+	})
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	b := newMDNSBrowser()
+	b.browse(ctx, []mdnsConn{{conn, group}})
+
+	want := map[string]mdnsHost{
+		"192.168.1.50": {
+			Name:     "raspberrypi.local",
+			Services: []Service{{Instance: "raspberrypi", Type: "_ssh._tcp", Port: 22}},
+		},
+		"127.0.0.1": {
+			Services: []Service{{Instance: "Office Printer", Type: "_ipp._tcp", TXT: []string{"rp=ipp/print", "ty=Example"}}},
+		},
+	}
+	hosts := b.hosts()
+	if !reflect.DeepEqual(hosts, want) {
+		t.Fatalf("hosts() = %+v\nwant %+v", hosts, want)
+	}
+
+	devices := []Device{{IP: "192.168.1.50"}, {IP: "127.0.0.1"}, {IP: "192.168.1.99"}}
+	attachMDNS(devices, hosts)
+	if d := devices[0]; d.Hostname != "raspberrypi.local" || d.HostnameSource != HostnameMDNS || d.MDNSName != "raspberrypi.local" || len(d.Services) != 1 {
+		t.Errorf("named device: %+v", d)
+	}
+	if d := devices[1]; d.Hostname != "" || d.HostnameSource != "" || len(d.Services) != 1 {
+		t.Errorf("device found through its responder address: %+v", d)
+	}
+	if d := devices[2]; d.Hostname != "" || d.Services != nil {
+		t.Errorf("device not announced: %+v", d)
+	}
+}
+
+func TestMDNSRecord(t *testing.T) {
+	b := newMDNSBrowser()
+	msg := mdnsResponse([]dnsmessage.Resource{
+		mdnsAnswer(serviceEnumeration, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_HTTP._tcp.local.")}),
+		mdnsAnswer(serviceEnumeration, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_http._tcp.local.")}),
+		mdnsAnswer("50.1.168.192.in-addr.arpa.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("raspberrypi.local.")}),
+		mdnsAnswer("_http._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Web UI._http._tcp.local.")}),
+	}, []dnsmessage.Resource{
+		mdnsAnswer("Web UI._http._tcp.local.", &dnsmessage.SRVResource{Target: dnsmessage.MustNewName("Pi.local."), Port: 80}),
+		mdnsAnswer("pi.local.", &dnsmessage.AAAAResource{AAAA: [16]byte{0xfe, 0x80, 15: 1}}),
+		mdnsAnswer("pi.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 50}}),
+		mdnsAnswer("pi.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 50}}),
+	})
+
+	if got := b.record(msg, "192.168.1.50"); !reflect.DeepEqual(got, []string{"_http._tcp.local."}) {
+		t.Errorf("new types %v, want one despite the case difference", got)
+	}
+	if got := b.record(msg, "192.168.1.50"); got != nil {
+		t.Errorf("new types %v on a repeated response", got)
+	}
+
+	if inst, ok := b.instances["web ui._http._tcp.local."]; !ok || inst.Name != "Web UI._http._tcp.local." || inst.Sender != "192.168.1.50" {
+		t.Errorf("instance %+v, %v", inst, ok)
+	}
+	if len(b.instances) != 1 {
+		t.Errorf("reverse PTR records were taken as instances: %v", b.instances)
+	}
+	if srv := b.srv["web ui._http._tcp.local."]; srv != (srvInfo{Target: "pi.local.", Port: 80}) {
+		t.Errorf("srv %+v", srv)
+	}
+	if addrs := b.addrs["pi.local."]; !reflect.DeepEqual(addrs, []string{"fe80::1", "192.168.1.50"}) {
+		t.Errorf("addresses %v", addrs)
+	}
+
+	hosts := b.hosts()
+	for _, addr := range []string{"fe80::1", "192.168.1.50"} {
+		h := hosts[addr]
+		if h.Name != "pi.local" || len(h.Services) != 1 || h.Services[0].Instance != "Web UI" || h.Services[0].Port != 80 {
+			t.Errorf("hosts()[%s] = %+v", addr, h)
+		}
+	}
+}
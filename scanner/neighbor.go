@@ -0,0 +1,286 @@
+package scanner
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// neighbor is a single entry from the operating system's IP-to-MAC neighbor table
+type neighbor struct {
+	IP        string // Address without zone
+	MAC       string // Normalized xx:xx:xx:xx:xx:xx
+	Interface string // Interface name, when the source reports one
+}
+
+// key returns the address used to match a neighbor against discovered hosts.
+// Link-local IPv6 addresses carry their interface as the zone (fe80::1%eth0).
+func (n neighbor) key() string {
+	addr, err := netip.ParseAddr(n.IP)
+	if err == nil && addr.Is6() && addr.IsLinkLocalUnicast() && n.Interface != "" {
+		return n.IP + "%" + n.Interface
+	}
+	return n.IP
+}
+
+// neighborSource reads the system neighbor table (ARP for IPv4, NDP for IPv6).
+// Each platform provides its own implementation via defaultNeighborSource.
+type neighborSource interface {
+	Neighbors(family string) ([]neighbor, error)
+}
+
+// identifyDevices identifies each responsive host, taking its MAC address from the
+// probe that found it or, failing that, from the neighbor table
+func identifyDevices(src neighborSource, hosts []liveHost, family string) ([]Device, error) {
+	macs := make(map[string]string)
+	needTable := false
+	for _, h := range hosts {
+		if h.Result.MAC == "" {
+			needTable = true
+			break
+		}
+	}
+
+	var tableErr error
+	if needTable {
+		neighbors, err := src.Neighbors(family)
+		if err != nil {
+			tableErr = err
+		}
+		for _, n := range neighbors {
+			macs[n.key()] = n.MAC
+		}
+	}
+
+	var devices []Device
+	for _, h := range hosts {
+		mac := h.Result.MAC
+		if mac == "" {
+			mac = macs[h.IP]
+		}
+		if mac == "" {
+			continue
+		}
+		dev := newDevice(h.IP, mac, family)
+		dev.DiscoveredBy = h.Result.Method
+		dev.Evidence = h.Result.Evidence
+		dev.RTTMillis = float64(h.Result.RTT.Microseconds()) / 1000
+		devices = append(devices, dev)
+	}
+
+	sortDevices(devices)
+	return devices, tableErr
+}
+
+// sortDevices orders devices by address, IPv4 before IPv6
+func sortDevices(devices []Device) {
+	sort.SliceStable(devices, func(i, j int) bool {
+		a, errA := netip.ParseAddr(devices[i].IP)
+		b, errB := netip.ParseAddr(devices[j].IP)
+		if errA != nil || errB != nil {
+			return devices[i].IP < devices[j].IP
+		}
+		return a.Less(b)
+	})
+}
+
+// parseProcNetARP parses the Linux /proc/net/arp table:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        eth0
+//
+// Entries with flags 0x0 are incomplete and are skipped.
+func parseProcNetARP(r io.Reader) []neighbor {
+	var neighbors []neighbor
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] == "IP" || fields[2] == "0x0" {
+			continue
+		}
+		if n, ok := makeNeighbor(fields[0], fields[3], fields[5]); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// parseBSDARP parses `arp -an` output from macOS and the BSDs:
+//
+//	? (192.168.1.1) at aa:bb:cc:dd:ee:ff on en0 ifscope [ethernet]
+//	router.lan (192.168.1.1) at 0:1b:2:3c:4:5 on em0 expires in 1190 seconds [ethernet]
+//	? (192.168.1.7) at (incomplete) on en0 ifscope [ethernet]
+//
+// Lines are tokenized rather than split on substrings, so hostnames containing
+// "at" or "on" do not confuse the parser.
+func parseBSDARP(r io.Reader) []neighbor {
+	var neighbors []neighbor
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		var ip, mac, iface string
+		for i, f := range fields {
+			switch {
+			case ip == "" && strings.HasPrefix(f, "(") && strings.HasSuffix(f, ")"):
+				ip = strings.Trim(f, "()")
+			case ip != "" && f == "at" && i+1 < len(fields) && mac == "":
+				mac = fields[i+1]
+			case ip != "" && f == "on" && i+1 < len(fields) && iface == "":
+				iface = fields[i+1]
+			}
+		}
+
+		if n, ok := makeNeighbor(ip, mac, iface); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// parseBSDNDP parses `ndp -an` output from macOS and the BSDs:
+//
+//	Neighbor                        Linklayer Address  Netif Expire    St Flgs Prbs
+//	fe80::1%en0                     aa:bb:cc:dd:ee:ff    en0 23h59m58s S  R
+//	fe80::5%en0                     (incomplete)         en0 expired   N
+func parseBSDNDP(r io.Reader) []neighbor {
+	var neighbors []neighbor
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] == "Neighbor" {
+			continue
+		}
+		if n, ok := makeNeighbor(fields[0], fields[1], fields[2]); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// parseWindowsARP parses `arp -a` output from Windows:
+//
+//	Interface: 192.168.1.5 --- 0xb
+//	  Internet Address      Physical Address      Type
+//	  192.168.1.1           aa-bb-cc-dd-ee-ff     dynamic
+//	  192.168.1.255         ff-ff-ff-ff-ff-ff     static
+//
+// Windows does not name interfaces in this output, so Interface is left empty.
+// Broadcast and multicast entries are skipped.
+func parseWindowsARP(r io.Reader) []neighbor {
+	var neighbors []neighbor
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] == "Interface:" || fields[0] == "Internet" {
+			continue
+		}
+		if n, ok := makeNeighbor(fields[0], fields[1], ""); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// parseWindowsNetshNeighbors parses `netsh interface ipv6 show neighbors` output:
+//
+//	Interface 12: Ethernet
+//
+//	Internet Address                              Physical Address   Type
+//	--------------------------------------------  -----------------  -----------
+//	fe80::1                                       aa-bb-cc-dd-ee-ff  Reachable (Router)
+//	ff02::1                                       33-33-00-00-00-01  Permanent
+func parseWindowsNetshNeighbors(r io.Reader) []neighbor {
+	var (
+		neighbors []neighbor
+		iface     string
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Interface ") {
+			if _, name, ok := strings.Cut(line, ": "); ok {
+				iface = strings.TrimSpace(name)
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] == "Unreachable" || fields[2] == "Incomplete" {
+			continue
+		}
+		if n, ok := makeNeighbor(fields[0], fields[1], iface); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// makeNeighbor validates and normalizes a raw neighbor table entry. Entries without
+// a usable unicast MAC (incomplete, broadcast, multicast) are rejected.
+func makeNeighbor(ip, mac, iface string) (neighbor, bool) {
+	ip, zone, _ := strings.Cut(ip, "%")
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return neighbor{}, false
+	}
+	if iface == "" {
+		iface = zone
+	}
+
+	mac = normalizeMAC(mac)
+	if !isUsableMAC(mac) {
+		return neighbor{}, false
+	}
+
+	return neighbor{IP: addr.Unmap().String(), MAC: mac, Interface: iface}, true
+}
+
+// isUsableMAC reports whether a neighbor table entry holds a real unicast hardware address
+func isUsableMAC(mac string) bool {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return false
+	}
+	if hw[0]&0x01 != 0 {
+		return false // broadcast or multicast
+	}
+	for _, b := range hw {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMAC converts separator variants and unpadded octets (as printed by BSD
+// tools, e.g. 0:1b:2:3c:4:5) into lower-case xx:xx:xx:xx:xx:xx form
+func normalizeMAC(mac string) string {
+	parts := strings.FieldsFunc(strings.ToLower(mac), func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return mac
+	}
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// filterFamily keeps only neighbors of the requested address family
+func filterFamily(neighbors []neighbor, family string) []neighbor {
+	var out []neighbor
+	for _, n := range neighbors {
+		addr, err := netip.ParseAddr(n.IP)
+		if err != nil {
+			continue
+		}
+		if (family == AddressFamilyIPv4) == addr.Is4() {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// bsdNeighborSource reads neighbors from the arp(8) and ndp(8) utilities that ship
+// with macOS and the BSDs
+type bsdNeighborSource struct{}
+
+// defaultNeighborSource returns the neighbor table reader for this platform
+func defaultNeighborSource() neighborSource {
+	return bsdNeighborSource{}
+}
+
+// Neighbors returns the system neighbor table for the given address family
+func (bsdNeighborSource) Neighbors(family string) ([]neighbor, error) {
+	if family == AddressFamilyIPv6 {
+		out, err := exec.Command("ndp", "-an").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run ndp: %w", err)
+		}
+		return parseBSDNDP(bytes.NewReader(out)), nil
+	}
+
+	// -n skips reverse lookups, which are slow and irrelevant here
+	out, err := exec.Command("arp", "-an").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run arp: %w", err)
+	}
+	return parseBSDARP(bytes.NewReader(out)), nil
+}
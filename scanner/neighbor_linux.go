@@ -0,0 +1,123 @@
+//go:build linux
+
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+)
+
+// Neighbor attribute types and states from linux/neighbour.h
+const (
+	ndaDst    = 1
+	ndaLLAddr = 2
+
+	nudIncomplete = 0x01
+	nudFailed     = 0x20
+	nudNoARP      = 0x40
+
+	ndmsgLen = 12 // struct ndmsg
+)
+
+// linuxNeighborSource reads neighbors over rtnetlink, falling back to /proc/net/arp
+// for IPv4 when netlink is unavailable (e.g. restricted containers)
+type linuxNeighborSource struct{}
+
+// defaultNeighborSource returns the neighbor table reader for this platform
+func defaultNeighborSource() neighborSource {
+	return linuxNeighborSource{}
+}
+
+// Neighbors returns the kernel neighbor table for the given address family
+func (linuxNeighborSource) Neighbors(family string) ([]neighbor, error) {
+	af := syscall.AF_INET
+	if family == AddressFamilyIPv6 {
+		af = syscall.AF_INET6
+	}
+
+	neighbors, err := netlinkNeighbors(af)
+	if err == nil {
+		return neighbors, nil
+	}
+	if family == AddressFamilyIPv6 {
+		return nil, err
+	}
+
+	file, ferr := os.Open("/proc/net/arp")
+	if ferr != nil {
+		return nil, fmt.Errorf("%v; fallback failed: %w", err, ferr)
+	}
+	defer file.Close()
+	return parseProcNetARP(file), nil
+}
+
+// netlinkNeighbors dumps the neighbor table with an RTM_GETNEIGH request
+func netlinkNeighbors(af int) ([]neighbor, error) {
+	tab, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, af)
+	if err != nil {
+		return nil, fmt.Errorf("netlink neighbor dump failed: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(tab)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse netlink response: %w", err)
+	}
+
+	ifNames := make(map[int32]string)
+	var neighbors []neighbor
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWNEIGH || len(m.Data) < ndmsgLen {
+			continue
+		}
+
+		ifIndex := int32(binary.NativeEndian.Uint32(m.Data[4:8]))
+		state := binary.NativeEndian.Uint16(m.Data[8:10])
+		if state&(nudIncomplete|nudFailed|nudNoARP) != 0 {
+			continue
+		}
+
+		var ip, mac []byte
+		for attrs := m.Data[ndmsgLen:]; len(attrs) >= syscall.SizeofRtAttr; {
+			attrLen := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			attrType := binary.NativeEndian.Uint16(attrs[2:4])
+			if attrLen < syscall.SizeofRtAttr || attrLen > len(attrs) {
+				break
+			}
+			value := attrs[syscall.SizeofRtAttr:attrLen]
+			switch attrType {
+			case ndaDst:
+				ip = value
+			case ndaLLAddr:
+				mac = value
+			}
+			attrs = attrs[min(rtaAlign(attrLen), len(attrs)):]
+		}
+
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok || len(mac) != 6 {
+			continue
+		}
+
+		name, ok := ifNames[ifIndex]
+		if !ok {
+			if iface, err := net.InterfaceByIndex(int(ifIndex)); err == nil {
+				name = iface.Name
+			}
+			ifNames[ifIndex] = name
+		}
+
+		if n, ok := makeNeighbor(addr.String(), net.HardwareAddr(mac).String(), name); ok {
+			neighbors = append(neighbors, n)
+		}
+	}
+
+	return neighbors, nil
+}
+
+// rtaAlign rounds an attribute length up to the 4-byte netlink alignment
+func rtaAlign(n int) int {
+	return (n + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package scanner
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// unsupportedNeighborSource is used on platforms without a neighbor table reader
+type unsupportedNeighborSource struct{}
+
+// defaultNeighborSource returns the neighbor table reader for this platform
+func defaultNeighborSource() neighborSource {
+	return unsupportedNeighborSource{}
+}
+
+// Neighbors always fails on unsupported platforms
+func (unsupportedNeighborSource) Neighbors(string) ([]neighbor, error) {
+	return nil, fmt.Errorf("reading the neighbor table is not supported on %s", runtime.GOOS)
+}
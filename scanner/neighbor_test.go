@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNeighborParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		parse   func(io.Reader) []neighbor
+		want    []neighbor
+	}{
+		{
+			name:    "linux /proc/net/arp",
+			fixture: "proc_net_arp.txt",
+			parse:   parseProcNetARP,
+			want: []neighbor{
+				{IP: "192.168.1.1", MAC: "b8:27:eb:12:34:56", Interface: "eth0"},
+				{IP: "192.168.1.23", MAC: "dc:a6:32:ab:cd:ef", Interface: "eth0"},
+				{IP: "10.8.0.7", MAC: "3c:22:fb:01:02:03", Interface: "wlan0"},
+			},
+		},
+		{
+			name:    "macOS arp -an",
+			fixture: "arp_darwin.txt",
+			parse:   parseBSDARP,
+			want: []neighbor{
+				{IP: "192.168.1.1", MAC: "b8:27:eb:12:34:56", Interface: "en0"},
+				{IP: "192.168.1.2", MAC: "00:1b:02:3c:04:05", Interface: "en0"},
+				{IP: "192.168.1.3", MAC: "dc:a6:32:ab:cd:ef", Interface: "en1"},
+			},
+		},
+		{
+			name:    "FreeBSD arp -an",
+			fixture: "arp_freebsd.txt",
+			parse:   parseBSDARP,
+			want: []neighbor{
+				{IP: "10.0.0.1", MAC: "00:0d:b9:41:6a:10", Interface: "em0"},
+				{IP: "10.0.0.254", MAC: "00:0c:29:0a:0b:0c", Interface: "em1"},
+			},
+		},
+		{
+			name:    "macOS ndp -an",
+			fixture: "ndp_darwin.txt",
+			parse:   parseBSDNDP,
+			want: []neighbor{
+				{IP: "fe80::1", MAC: "b8:27:eb:12:34:56", Interface: "en0"},
+				{IP: "2001:db8::23", MAC: "dc:a6:32:ab:cd:ef", Interface: "en0"},
+			},
+		},
+		{
+			name:    "windows arp -a",
+			fixture: "arp_windows.txt",
+			parse:   parseWindowsARP,
+			want: []neighbor{
+				{IP: "192.168.1.1", MAC: "b8:27:eb:12:34:56"},
+				{IP: "192.168.1.23", MAC: "dc:a6:32:ab:cd:ef"},
+				{IP: "10.8.0.7", MAC: "3c:22:fb:01:02:03"},
+			},
+		},
+		{
+			name:    "windows netsh neighbors",
+			fixture: "netsh_neighbors.txt",
+			parse:   parseWindowsNetshNeighbors,
+			want: []neighbor{
+				{IP: "fe80::1", MAC: "b8:27:eb:12:34:56", Interface: "Ethernet"},
+				{IP: "fe80::23", MAC: "dc:a6:32:ab:cd:ef", Interface: "Ethernet"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if got := tt.parse(f); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeighborKey(t *testing.T) {
+	tests := []struct {
+		n    neighbor
+		want string
+	}{
+		{neighbor{IP: "fe80::1", Interface: "eth0"}, "fe80::1%eth0"},
+		{neighbor{IP: "fe80::1"}, "fe80::1"},
+		{neighbor{IP: "2001:db8::1", Interface: "eth0"}, "2001:db8::1"},
+		{neighbor{IP: "192.168.1.1", Interface: "eth0"}, "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		if got := tt.n.key(); got != tt.want {
+			t.Errorf("%+v.key() = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFilterFamily(t *testing.T) {
+	neighbors := []neighbor{
+		{IP: "192.168.1.1", MAC: "b8:27:eb:12:34:56"},
+		{IP: "fe80::1", MAC: "b8:27:eb:12:34:56"},
+	}
+	if got := filterFamily(neighbors, AddressFamilyIPv4); len(got) != 1 || got[0].IP != "192.168.1.1" {
+		t.Errorf("ipv4: got %+v", got)
+	}
+	if got := filterFamily(neighbors, AddressFamilyIPv6); len(got) != 1 || got[0].IP != "fe80::1" {
+		t.Errorf("ipv6: got %+v", got)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// windowsNeighborSource reads neighbors from `arp -a` and netsh
+type windowsNeighborSource struct{}
+
+// defaultNeighborSource returns the neighbor table reader for this platform
+func defaultNeighborSource() neighborSource {
+	return windowsNeighborSource{}
+}
+
+// Neighbors returns the system neighbor table for the given address family
+func (windowsNeighborSource) Neighbors(family string) ([]neighbor, error) {
+	if family == AddressFamilyIPv6 {
+		out, err := exec.Command("netsh", "interface", "ipv6", "show", "neighbors").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run netsh: %w", err)
+		}
+		return parseWindowsNetshNeighbors(bytes.NewReader(out)), nil
+	}
+
+	out, err := exec.Command("arp", "-a").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run arp: %w", err)
+	}
+	return filterFamily(parseWindowsARP(bytes.NewReader(out)), AddressFamilyIPv4), nil
+}
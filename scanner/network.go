@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// Network is a local IPv4 address, its subnet and the interface it belongs to
+type Network struct {
+	Interface string
+	IP        string
+	Prefix    netip.Prefix
+}
+
+// LocalNetworks returns every IPv4 network on an up, non-loopback interface
+func LocalNetworks() ([]Network, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var networks []Network
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range addrs {
+			if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+				ip4 := ipnet.IP.To4()
+				if ip4 == nil {
+					continue
+				}
+				ones, _ := ipnet.Mask.Size()
+				addr, _ := netip.AddrFromSlice(ip4)
+				networks = append(networks, Network{
+					Interface: iface.Name,
+					IP:        addr.String(),
+					Prefix:    netip.PrefixFrom(addr, ones).Masked(),
+				})
+			}
+		}
+	}
+
+	return networks, nil
+}
+
+// InterfaceFor returns the interface whose network contains ip, which is the interface
+// ARP requests for it have to leave through
+func InterfaceFor(networks []Network, ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	for _, n := range networks {
+		if n.Prefix.Contains(addr) {
+			return n.Interface
+		}
+	}
+	return ""
+}
+
+// MulticastInterface returns the first up, non-loopback interface that supports multicast
+func MulticastInterface() (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 && iface.Flags&net.FlagMulticast != 0 {
+			return iface.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no interface available for IPv6 discovery")
+}
@@ -0,0 +1,234 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ping/ping"
+)
+
+// Names of the built-in probers, as reported in ProbeResult.Method
+const (
+	ProbeICMP = "icmp"
+	ProbeARP  = "arp"
+	ProbeTCP  = "tcp"
+)
+
+// ProbeResult is the outcome of probing a single address
+type ProbeResult struct {
+	Alive    bool
+	RTT      time.Duration
+	MAC      string // Hardware address, if the probe learned it
+	Method   string // Name of the prober that got the answer
+	Evidence string // What proved the host is up, e.g. "tcp/22 refused"
+}
+
+// Prober checks whether a single address is up. Implementations must be safe for
+// concurrent use since a Scanner calls them from many goroutines.
+type Prober interface {
+	Name() string
+	Probe(ctx context.Context, ip string) ProbeResult
+}
+
+// ProbeChain runs probers in order and stops at the first one that gets an answer,
+// e.g. ICMP first, then TCP, then ARP
+type ProbeChain []Prober
+
+// Name returns the names of the chained probers
+func (c ProbeChain) Name() string {
+	names := make([]string, 0, len(c))
+	for _, p := range c {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Probe tries each prober until one reports the host alive
+func (c ProbeChain) Probe(ctx context.Context, ip string) ProbeResult {
+	for _, p := range c {
+		if result := p.Probe(ctx, ip); result.Alive {
+			if result.Method == "" {
+				result.Method = p.Name()
+			}
+			return result
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return ProbeResult{}
+}
+
+// ICMPProber checks liveness with unprivileged ICMP echo requests
+type ICMPProber struct {
+	Timeout time.Duration // How long to wait for replies
+	Count   int           // Echo requests sent per host
+}
+
+func (p ICMPProber) Name() string { return ProbeICMP }
+
+func (p ICMPProber) Probe(ctx context.Context, ip string) ProbeResult {
+	rtt, ok := pingIP(ctx, ip, p.Timeout, max(p.Count, 1))
+	if !ok {
+		return ProbeResult{}
+	}
+	return ProbeResult{Alive: true, RTT: rtt, Method: ProbeICMP, Evidence: "echo reply"}
+}
+
+// Pings an IP address with proper context and timeout, returning the best round-trip time
+func pingIP(ctx context.Context, ipAddress string, timeout time.Duration, count int) (time.Duration, bool) {
+	pinger, err := ping.NewPinger(ipAddress)
+	if err != nil {
+		return 0, false
+	}
+	defer pinger.Stop()
+
+	pinger.Count = count
+	pinger.Timeout = timeout
+	pinger.SetPrivileged(false) // Use unprivileged ICMP
+
+	received := false
+	var rtt time.Duration
+	pinger.OnRecv = func(pkt *ping.Packet) {
+		if !received || pkt.Rtt < rtt {
+			rtt = pkt.Rtt
+		}
+		received = true
+	}
+
+	// Run with context
+	done := make(chan bool, 1)
+	go func() {
+		err = pinger.Run()
+		done <- true
+	}()
+
+	select {
+	case <-ctx.Done():
+		pinger.Stop()
+		return 0, false
+	case <-done:
+		return rtt, received && err == nil
+	}
+}
+
+// ARPProber checks liveness with ARP requests and records the MAC from the reply.
+// It holds a raw socket, so callers must Close it when the scan is done.
+type ARPProber struct {
+	resolver arpResolver
+	timeout  time.Duration
+	count    int
+}
+
+// NewARPProber opens a raw ARP socket on the named interface. Each probe sends up to
+// count requests and waits timeout for each reply. Only Linux is supported, and the
+// process needs CAP_NET_RAW.
+func NewARPProber(ifaceName string, timeout time.Duration, count int) (*ARPProber, error) {
+	resolver, err := newARPResolver(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	return &ARPProber{resolver: resolver, timeout: timeout, count: max(count, 1)}, nil
+}
+
+func (p *ARPProber) Name() string { return ProbeARP }
+
+func (p *ARPProber) Probe(ctx context.Context, ip string) ProbeResult {
+	start := time.Now()
+	mac, ok := p.resolver.resolve(ctx, ip, p.timeout, p.count)
+	if !ok {
+		return ProbeResult{}
+	}
+	return ProbeResult{Alive: true, RTT: time.Since(start), MAC: mac, Method: ProbeARP, Evidence: "arp reply"}
+}
+
+// Close releases the raw socket
+func (p *ARPProber) Close() error {
+	return p.resolver.Close()
+}
+
+// TCPProber checks liveness by connecting to a list of TCP ports. A completed
+// handshake and a refused connection (RST) both prove the host is up.
+type TCPProber struct {
+	Ports   []int
+	Timeout time.Duration
+}
+
+func (p TCPProber) Name() string { return ProbeTCP }
+
+func (p TCPProber) Probe(ctx context.Context, ip string) ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	results := make(chan ProbeResult, len(p.Ports))
+	for _, port := range p.Ports {
+		go func(port int) {
+			results <- tcpPortProbe(ctx, ip, port)
+		}(port)
+	}
+
+	for range p.Ports {
+		if result := <-results; result.Alive {
+			return result
+		}
+	}
+	return ProbeResult{}
+}
+
+// tcpPortProbe reports whether the host responded on a port, either by accepting
+// the connection or by refusing it
+func tcpPortProbe(ctx context.Context, ip string, port int) ProbeResult {
+	rtt, err := dialPort(ctx, ip, port)
+	if err == nil {
+		return ProbeResult{Alive: true, RTT: rtt, Method: ProbeTCP, Evidence: fmt.Sprintf("tcp/%d open", port)}
+	}
+	if isConnRefused(err) {
+		return ProbeResult{Alive: true, RTT: rtt, Method: ProbeTCP, Evidence: fmt.Sprintf("tcp/%d refused", port)}
+	}
+	return ProbeResult{}
+}
+
+// dialPort opens and immediately closes a TCP connection, returning how long the
+// handshake (or the refusal) took
+func dialPort(ctx context.Context, ip string, port int) (time.Duration, error) {
+	var d net.Dialer
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	rtt := time.Since(start)
+	if err != nil {
+		return rtt, err
+	}
+	conn.Close()
+	return rtt, nil
+}
+
+// openPorts returns which of the given ports accept a TCP connection, in order
+func openPorts(ctx context.Context, ip string, ports []int, timeout time.Duration) []int {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	open := make([]bool, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			_, err := dialPort(ctx, ip, port)
+			open[i] = err == nil
+		}(i, port)
+	}
+	wg.Wait()
+
+	var found []int
+	for i, port := range ports {
+		if open[i] {
+			found = append(found, port)
+		}
+	}
+	return found
+}
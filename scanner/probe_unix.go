@@ -0,0 +1,13 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isConnRefused reports whether a dial failed because the host answered with a RST
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package scanner
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isConnRefused reports whether a dial failed because the host answered with a RST
+func isConnRefused(err error) bool {
+	return errors.Is(err, windows.WSAECONNREFUSED)
+}
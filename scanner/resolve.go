@@ -0,0 +1,366 @@
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Names of the built-in name resolvers, as reported in Device.HostnameSource
+const (
+	ResolverDNS     = "dns"
+	ResolverNetBIOS = "netbios"
+	ResolverLLMNR   = "llmnr"
+)
+
+// Sources reported in Device.HostnameSource for names not found by a resolver
+const (
+	HostnameMDNS = "mdns" // Heard over mDNS
+	HostnameDHCP = "dhcp" // From a DHCP lease file
+)
+
+// DefaultDNSTimeout bounds the reverse DNS lookup of each device. System resolvers
+// retry for far longer than is worth waiting in a scan.
+const DefaultDNSTimeout = 2 * time.Second
+
+// DefaultResolveCacheTTL is how long a Scanner remembers the names it resolved
+const DefaultResolveCacheTTL = 5 * time.Minute
+
+// Ports of the NetBIOS name service and LLMNR
+const (
+	netbiosPort = 137
+	llmnrPort   = 5355
+)
+
+// NameResult is what a resolver learned about an address
+type NameResult struct {
+	Name      string
+	Source    string // Name of the resolver that found it
+	Workgroup string // NetBIOS workgroup or domain, if known
+}
+
+// NameResolver looks up the name of a single address. Implementations must be safe
+// for concurrent use since a Scanner calls them from many goroutines.
+type NameResolver interface {
+	Name() string
+	Resolve(ctx context.Context, ip string) NameResult
+}
+
+// ResolverChain asks all of its resolvers at once and keeps the name found by the
+// earliest resolver in the chain, e.g. reverse DNS over NetBIOS over LLMNR. The
+// workgroup comes from whichever resolver reports one.
+type ResolverChain []NameResolver
+
+// Name returns the names of the chained resolvers
+func (c ResolverChain) Name() string {
+	names := make([]string, 0, len(c))
+	for _, r := range c {
+		names = append(names, r.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Resolve runs every resolver in parallel and combines their answers in chain order
+func (c ResolverChain) Resolve(ctx context.Context, ip string) NameResult {
+	results := make([]NameResult, len(c))
+	var wg sync.WaitGroup
+	for i, r := range c {
+		wg.Add(1)
+		go func(i int, r NameResolver) {
+			defer wg.Done()
+			results[i] = r.Resolve(ctx, ip)
+			if results[i].Name != "" && results[i].Source == "" {
+				results[i].Source = r.Name()
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	var combined NameResult
+	for _, result := range results {
+		if combined.Name == "" && result.Name != "" {
+			combined.Name, combined.Source = result.Name, result.Source
+		}
+		if combined.Workgroup == "" {
+			combined.Workgroup = result.Workgroup
+		}
+	}
+	return combined
+}
+
+// ResolverCache remembers the results of another resolver for a while, keyed by
+// address, so repeated scans with the same Scanner do not ask slow resolvers again
+type ResolverCache struct {
+	resolver NameResolver
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedName
+}
+
+type cachedName struct {
+	result  NameResult
+	expires time.Time
+}
+
+// NewResolverCache wraps a resolver in a cache whose entries expire after ttl
+func NewResolverCache(r NameResolver, ttl time.Duration) *ResolverCache {
+	return &ResolverCache{resolver: r, ttl: ttl, now: time.Now, entries: make(map[string]cachedName)}
+}
+
+// Name returns the name of the cached resolver
+func (c *ResolverCache) Name() string { return c.resolver.Name() }
+
+// Resolve returns the cached result for ip, asking the resolver if there is none
+// or it has expired. Lookups cut short by ctx are not cached.
+func (c *ResolverCache) Resolve(ctx context.Context, ip string) NameResult {
+	c.mu.Lock()
+	entry, ok := c.entries[ip]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.result
+	}
+
+	result := c.resolver.Resolve(ctx, ip)
+	if ctx.Err() == nil {
+		c.mu.Lock()
+		c.entries[ip] = cachedName{result, c.now().Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return result
+}
+
+// DNSResolver looks names up with reverse DNS, through the system resolver or a
+// given server. A router's DNS server often knows the names of its DHCP clients when
+// the system resolver does not.
+type DNSResolver struct {
+	Timeout time.Duration // Zero waits as long as the resolver does
+	Server  string        // "host" or "host:port"; empty uses the system resolver
+}
+
+func (r DNSResolver) Name() string { return ResolverDNS }
+
+func (r DNSResolver) Resolve(ctx context.Context, ip string) NameResult {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	// Reverse lookups never carry the IPv6 zone
+	ip, _, _ = strings.Cut(ip, "%")
+	names, err := r.resolver().LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return NameResult{}
+	}
+	return NameResult{Name: strings.TrimSuffix(names[0], "."), Source: ResolverDNS}
+}
+
+// resolver returns the net.Resolver that sends queries to the configured server
+func (r DNSResolver) resolver() *net.Resolver {
+	if r.Server == "" {
+		return net.DefaultResolver
+	}
+	server := r.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// NetBIOSResolver asks IPv4 hosts for their NetBIOS name table with a Node Status
+// request (RFC 1002 section 4.2.17). Windows answers by default, as does Samba's nmbd,
+// and the table also names the workgroup or domain.
+type NetBIOSResolver struct {
+	Timeout time.Duration
+}
+
+func (r NetBIOSResolver) Name() string { return ResolverNetBIOS }
+
+func (r NetBIOSResolver) Resolve(ctx context.Context, ip string) NameResult {
+	if addr, err := netip.ParseAddr(ip); err != nil || !addr.Is4() {
+		return NameResult{} // NetBIOS over TCP/IP is IPv4 only
+	}
+
+	id := uint16(rand.N(1 << 16))
+	var result NameResult
+	exchangeUDP(ctx, net.JoinHostPort(ip, strconv.Itoa(netbiosPort)), buildNodeStatusRequest(id), r.Timeout, func(reply []byte) bool {
+		name, workgroup, ok := parseNodeStatus(reply, id)
+		if ok {
+			result = NameResult{Name: name, Workgroup: workgroup}
+			if name != "" {
+				result.Source = ResolverNetBIOS
+			}
+		}
+		return ok
+	})
+	return result
+}
+
+// netbiosWildcard is "*" padded with NULs to 16 bytes in first-level encoding (RFC
+// 1001 section 14.1), where each nibble becomes a letter from 'A'. A node status
+// request asks for it.
+const netbiosWildcard = "CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+// buildNodeStatusRequest packs an NBSTAT query for the wildcard name
+func buildNodeStatusRequest(id uint16) []byte {
+	b := binary.BigEndian.AppendUint16(nil, id)
+	b = append(b, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0) // No flags, one question
+	b = append(b, byte(len(netbiosWildcard)))
+	b = append(b, netbiosWildcard...)
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(b, 0x21) // NBSTAT
+	b = binary.BigEndian.AppendUint16(b, 1)    // IN
+	return b
+}
+
+// parseNodeStatus reads the name table from a node status response. The host name
+// is the first unique name with the workstation suffix 0x00, the workgroup the first
+// group name with that suffix. ok is false if the packet is not a response to id.
+func parseNodeStatus(packet []byte, id uint16) (name, workgroup string, ok bool) {
+	if len(packet) < 12 || binary.BigEndian.Uint16(packet) != id || packet[2]&0x80 == 0 {
+		return "", "", false
+	}
+	if binary.BigEndian.Uint16(packet[6:]) == 0 {
+		return "", "", true // Answered, but with no records
+	}
+
+	// Skip the question name echoed in the answer: labels ending in a zero byte, or
+	// a compression pointer
+	off := 12
+	for off < len(packet) {
+		length := int(packet[off])
+		if length == 0 {
+			off++
+			break
+		}
+		if length&0xc0 == 0xc0 {
+			off += 2
+			break
+		}
+		off += 1 + length
+	}
+
+	// Type, class, TTL and data length precede the data
+	if off+10 > len(packet) || binary.BigEndian.Uint16(packet[off:]) != 0x21 {
+		return "", "", true
+	}
+	end := min(off+10+int(binary.BigEndian.Uint16(packet[off+8:])), len(packet))
+	rdata := packet[off+10 : end]
+	if len(rdata) == 0 {
+		return "", "", true
+	}
+
+	// Each entry is a 15-byte name padded with spaces, a suffix byte and two bytes of
+	// flags, whose top bit marks group names
+	entries := rdata[1:]
+	for i := 0; i < int(rdata[0]) && len(entries) >= 18; i++ {
+		entry := entries[:18]
+		entries = entries[18:]
+		if entry[15] != 0x00 {
+			continue
+		}
+		entryName := strings.TrimRight(string(entry[:15]), " \x00")
+		// A count larger than the table runs into the statistics that follow it
+		if entryName == "" || strings.ContainsFunc(entryName, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+			continue
+		}
+		if entry[16]&0x80 != 0 {
+			if workgroup == "" {
+				workgroup = entryName
+			}
+		} else if name == "" {
+			name = entryName
+		}
+	}
+	return name, workgroup, true
+}
+
+// LLMNRResolver sends a reverse (PTR) query to the host's LLMNR responder (RFC 4795).
+// Windows answers for its own addresses, as do systemd-resolved hosts.
+type LLMNRResolver struct {
+	Timeout time.Duration
+}
+
+func (r LLMNRResolver) Name() string { return ResolverLLMNR }
+
+func (r LLMNRResolver) Resolve(ctx context.Context, ip string) NameResult {
+	reverse, err := reverseName(ip)
+	if err != nil {
+		return NameResult{}
+	}
+	id := uint16(rand.N(1 << 16))
+	query, err := buildMDNSQuery(id, []dnsmessage.Question{ptrQuestion(reverse)})
+	if err != nil {
+		return NameResult{}
+	}
+
+	var result NameResult
+	exchangeUDP(ctx, net.JoinHostPort(ip, strconv.Itoa(llmnrPort)), query, r.Timeout, func(reply []byte) bool {
+		name, ok := parseLLMNRReply(reply, id, reverse)
+		if name != "" {
+			result = NameResult{Name: name, Source: ResolverLLMNR}
+		}
+		return ok
+	})
+	return result
+}
+
+// parseLLMNRReply returns the name in the PTR answer for the reverse name queried.
+// ok is false if the packet is not a response to id.
+func parseLLMNRReply(packet []byte, id uint16, reverse string) (name string, ok bool) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil || msg.ID != id || !msg.Response {
+		return "", false
+	}
+	for _, rr := range msg.Answers {
+		if ptr, ok := rr.Body.(*dnsmessage.PTRResource); ok && strings.EqualFold(rr.Header.Name.String(), reverse) {
+			return strings.TrimSuffix(ptr.PTR.String(), "."), true
+		}
+	}
+	return "", true
+}
+
+// exchangeUDP sends query to addr and passes replies to accept until it returns true
+// or timeout expires
+func exchangeUDP(ctx context.Context, addr string, query []byte, timeout time.Duration, accept func([]byte) bool) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		if accept(buf[:n]) {
+			return nil
+		}
+	}
+}
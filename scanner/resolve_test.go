@@ -0,0 +1,292 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Flags of node status entries: active names, with the top bit marking groups
+const (
+	nbUnique = 0x0400
+	nbGroup  = 0x8400
+)
+
+// nbName is the wildcard question name as it is echoed in node status responses
+var nbName = append(append([]byte{32}, netbiosWildcard...), 0)
+
+// nbEntry packs one entry of a node status name table
+func nbEntry(name string, suffix byte, flags uint16) []byte {
+	b := fmt.Appendf(nil, "%-15s", name)
+	b = append(b, suffix)
+	return binary.BigEndian.AppendUint16(b, flags)
+}
+
+// nodeStatusReply packs a node status response in the layout Windows and nmbd send:
+// the echoed question name, then an NBSTAT record whose data is the name count, the
+// entries and 46 bytes of statistics starting with the MAC address
+func nodeStatusReply(id uint16, name []byte, entries ...[]byte) []byte {
+	rdata := []byte{byte(len(entries))}
+	for _, e := range entries {
+		rdata = append(rdata, e...)
+	}
+	stats := make([]byte, 46)
+	copy(stats, []byte{0x00, 0x15, 0x5d, 0x01, 0x02, 0x03})
+	rdata = append(rdata, stats...)
+
+	b := binary.BigEndian.AppendUint16(nil, id)
+	b = append(b, 0x84, 0x00, 0, 0, 0, 1, 0, 0, 0, 0) // Authoritative response, one answer
+	b = append(b, name...)
+	b = append(b, 0x00, 0x21, 0x00, 0x01, 0, 0, 0, 0) // NBSTAT, IN, TTL 0
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+func TestBuildNodeStatusRequest(t *testing.T) {
+	want, _ := hex.DecodeString("1234" + "0000" + "0001" + "0000" + "0000" + "0000" +
+		"20" + hex.EncodeToString([]byte("CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")) + "00" +
+		"0021" + "0001")
+	if got := buildNodeStatusRequest(0x1234); !bytes.Equal(got, want) {
+		t.Errorf("buildNodeStatusRequest(0x1234) =\n%x\nwant\n%x", got, want)
+	}
+}
+
+func TestParseNodeStatus(t *testing.T) {
+	windows := nodeStatusReply(0x1234, nbName,
+		nbEntry("DESKTOP-AB12CD", 0x00, nbUnique),
+		nbEntry("WORKGROUP", 0x00, nbGroup),
+		nbEntry("DESKTOP-AB12CD", 0x20, nbUnique),
+		nbEntry("WORKGROUP", 0x1e, nbGroup),
+	)
+	// nmbd lists the browse master name first and may compress the question name
+	samba := nodeStatusReply(0x0042, []byte{0xc0, 0x0c},
+		nbEntry("\x01\x02__MSBROWSE__\x02", 0x01, nbGroup),
+		nbEntry("PI-NAS", 0x20, nbUnique),
+		nbEntry("PI-NAS", 0x00, nbUnique),
+		nbEntry("HOME", 0x00, nbGroup),
+		nbEntry("HOME", 0x1d, nbUnique),
+	)
+	// A domain controller has no workstation name of its own in this table
+	groupsOnly := nodeStatusReply(0x0001, nbName, nbEntry("CORP", 0x00, nbGroup), nbEntry("CORP", 0x1c, nbGroup))
+	empty := nodeStatusReply(0x0001, nbName)
+
+	noAnswers := slices.Clone(windows[:12])
+	noAnswers[7] = 0
+
+	notResponse := slices.Clone(windows)
+	notResponse[2] = 0x04
+
+	wrongType := slices.Clone(windows)
+	binary.BigEndian.PutUint16(wrongType[12+len(nbName):], 0x20) // NB instead of NBSTAT
+
+	padded := nodeStatusReply(0x1234, nbName, append([]byte("PI\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), 0x04, 0x00))
+
+	tests := []struct {
+		name            string
+		packet          []byte
+		id              uint16
+		host, workgroup string
+		ok              bool
+	}{
+		{"windows", windows, 0x1234, "DESKTOP-AB12CD", "WORKGROUP", true},
+		{"samba with a compressed name", samba, 0x0042, "PI-NAS", "HOME", true},
+		{"group names only", groupsOnly, 0x0001, "", "CORP", true},
+		{"empty name table", empty, 0x0001, "", "", true},
+		{"NUL padding", padded, 0x1234, "PI", "", true},
+		{"no answers", noAnswers, 0x1234, "", "", true},
+		{"wrong record type", wrongType, 0x1234, "", "", true},
+		{"mismatched ID", windows, 0x4321, "", "", false},
+		{"not a response", notResponse, 0x1234, "", "", false},
+		{"shorter than a header", windows[:11], 0x1234, "", "", false},
+		{"empty", nil, 0, "", "", false},
+		// Cut inside the question name, the record header and the second entry
+		{"truncated question", windows[:20], 0x1234, "", "", true},
+		{"truncated record header", windows[:12+len(nbName)+6], 0x1234, "", "", true},
+		{"truncated name table", windows[:12+len(nbName)+10+1+18+10], 0x1234, "DESKTOP-AB12CD", "", true},
+		{"truncated after the count", windows[:12+len(nbName)+10+1], 0x1234, "", "", true},
+	}
+	for _, tt := range tests {
+		host, workgroup, ok := parseNodeStatus(tt.packet, tt.id)
+		if host != tt.host || workgroup != tt.workgroup || ok != tt.ok {
+			t.Errorf("%s: parseNodeStatus = %q, %q, %v; want %q, %q, %v", tt.name, host, workgroup, ok, tt.host, tt.workgroup, tt.ok)
+		}
+	}
+}
+
+func TestParseNodeStatusCount(t *testing.T) {
+	// The count byte claims more entries than the data holds; the statistics that
+	// follow must not be read as names
+	packet := nodeStatusReply(0x1234, nbName, nbEntry("WORKGROUP", 0x00, nbGroup))
+	packet[12+len(nbName)+10] = 5
+	host, workgroup, ok := parseNodeStatus(packet, 0x1234)
+	if host != "" || workgroup != "WORKGROUP" || !ok {
+		t.Errorf("parseNodeStatus = %q, %q, %v", host, workgroup, ok)
+	}
+}
+
+func TestParseLLMNRReply(t *testing.T) {
+	const reverse = "5.1.168.192.in-addr.arpa."
+	reply := func(id uint16, response bool, answers ...dnsmessage.Resource) []byte {
+		msg := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: id, Response: response},
+			Questions: []dnsmessage.Question{ptrQuestion(reverse)},
+			Answers:   answers,
+		}
+		packed, err := msg.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return packed
+	}
+	answer := mdnsAnswer(reverse, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("DESKTOP-AB12CD.")})
+
+	tests := []struct {
+		name   string
+		packet []byte
+		host   string
+		ok     bool
+	}{
+		{"answer", reply(7, true, answer), "DESKTOP-AB12CD", true},
+		{"owner name in another case", reply(7, true, mdnsAnswer("5.1.168.192.IN-ADDR.ARPA.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("pi.")})), "pi", true},
+		{"no answer", reply(7, true), "", true},
+		{"answer for another address", reply(7, true, mdnsAnswer("6.1.168.192.in-addr.arpa.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("other.")})), "", true},
+		{"not a PTR record", reply(7, true, mdnsAnswer(reverse, &dnsmessage.AResource{A: [4]byte{192, 168, 1, 5}})), "", true},
+		{"wrong ID", reply(8, true, answer), "", false},
+		{"query instead of a response", reply(7, false, answer), "", false},
+		{"garbage", []byte{0, 7, 0x80}, "", false},
+	}
+	for _, tt := range tests {
+		host, ok := parseLLMNRReply(tt.packet, 7, reverse)
+		if host != tt.host || ok != tt.ok {
+			t.Errorf("%s: parseLLMNRReply = %q, %v; want %q, %v", tt.name, host, ok, tt.host, tt.ok)
+		}
+	}
+}
+
+// fakeNames is a resolver that answers every address with one result
+type fakeNames struct {
+	name   string
+	result NameResult
+}
+
+func (r fakeNames) Name() string { return r.name }
+
+func (r fakeNames) Resolve(ctx context.Context, ip string) NameResult { return r.result }
+
+func TestResolverChain(t *testing.T) {
+	dns := fakeNames{"dns", NameResult{}}
+	netbios := fakeNames{"netbios", NameResult{Name: "DESKTOP-AB12CD", Workgroup: "WORKGROUP"}}
+	llmnr := fakeNames{"llmnr", NameResult{Name: "desktop-ab12cd"}}
+
+	chain := ResolverChain{dns, llmnr, netbios}
+	if name := chain.Name(); name != "dns,llmnr,netbios" {
+		t.Errorf("Name() = %q", name)
+	}
+	// The earliest resolver with a name wins; the workgroup comes from any of them
+	want := NameResult{Name: "desktop-ab12cd", Source: "llmnr", Workgroup: "WORKGROUP"}
+	if got := chain.Resolve(context.Background(), "192.168.1.5"); got != want {
+		t.Errorf("Resolve() = %+v, want %+v", got, want)
+	}
+	if got := (ResolverChain{dns}).Resolve(context.Background(), "192.168.1.5"); got != (NameResult{}) {
+		t.Errorf("Resolve() without a name = %+v", got)
+	}
+}
+
+// countingResolver names every address and counts the lookups of each
+type countingResolver struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *countingResolver) Name() string { return "counting" }
+
+func (r *countingResolver) Resolve(ctx context.Context, ip string) NameResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[ip]++
+	return NameResult{Name: "host-" + ip}
+}
+
+func TestResolverCache(t *testing.T) {
+	counting := &countingResolver{calls: make(map[string]int)}
+	s := New(
+		WithProbers(fakeProber{
+			"10.0.0.1": {Alive: true, MAC: "b8:27:eb:00:00:01"},
+			"10.0.0.2": {Alive: true, MAC: "b8:27:eb:00:00:02"},
+		}),
+		WithResolvers(counting),
+		WithFingerprint(false),
+	)
+	s.neighbors = fakeNeighbors{}
+	cache := s.resolver.(*ResolverCache)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	scan := func() {
+		t.Helper()
+		result, err := s.Scan(context.Background(), []string{"10.0.0.1", "10.0.0.2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, dev := range result.Devices {
+			if dev.Hostname != "host-"+dev.IP || dev.HostnameSource != "counting" {
+				t.Errorf("device %s named %q by %q", dev.IP, dev.Hostname, dev.HostnameSource)
+			}
+		}
+	}
+	want := map[string]int{"10.0.0.1": 1, "10.0.0.2": 1}
+
+	// A second scan within the TTL asks no one
+	scan()
+	now = now.Add(DefaultResolveCacheTTL - time.Second)
+	scan()
+	if !reflect.DeepEqual(counting.calls, want) {
+		t.Errorf("lookups within the TTL %v, want %v", counting.calls, want)
+	}
+
+	// Once the entries expire, the next scan asks again
+	now = now.Add(2 * time.Second)
+	scan()
+	want = map[string]int{"10.0.0.1": 2, "10.0.0.2": 2}
+	if !reflect.DeepEqual(counting.calls, want) {
+		t.Errorf("lookups after the TTL %v, want %v", counting.calls, want)
+	}
+}
+
+func TestResolverCacheCancelled(t *testing.T) {
+	cache := NewResolverCache(fakeResolver{"10.0.0.1": "slow"}, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cache.Resolve(ctx, "10.0.0.1")
+	if len(cache.entries) != 0 {
+		t.Errorf("a cancelled lookup was cached: %v", cache.entries)
+	}
+}
+
+func TestResolverCacheDisabled(t *testing.T) {
+	counting := &countingResolver{calls: make(map[string]int)}
+	s := New(
+		WithProbers(fakeProber{"10.0.0.1": {Alive: true, MAC: "b8:27:eb:00:00:01"}}),
+		WithResolvers(counting),
+		WithResolveCacheTTL(0),
+		WithFingerprint(false),
+	)
+	s.neighbors = fakeNeighbors{}
+	for range 2 {
+		if _, err := s.Scan(context.Background(), []string{"10.0.0.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counting.calls["10.0.0.1"] != 2 {
+		t.Errorf("lookups without a cache %v, want 2", counting.calls)
+	}
+}
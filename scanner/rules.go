@@ -0,0 +1,201 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/james-see/gofindpi/data"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule assigns a category to devices that match all of its conditions. Within a
+// condition, any listed value may match: a device matches mac_prefix: [a, b] if its
+// MAC starts with a or b.
+type Rule struct {
+	Name     string    `yaml:"name" json:"name"`
+	Category string    `yaml:"category" json:"category"`
+	Priority int       `yaml:"priority" json:"priority"`
+	Match    RuleMatch `yaml:"match" json:"match"`
+
+	vendor   *regexp.Regexp
+	hostname *regexp.Regexp
+	prefixes []string
+}
+
+// RuleMatch holds the conditions of a rule. Empty conditions are ignored, but a rule
+// needs at least one.
+type RuleMatch struct {
+	MACPrefix []string `yaml:"mac_prefix" json:"mac_prefix"` // Any notation, e.g. "b8:27:eb" or "70B3D5123"
+	Vendor    string   `yaml:"vendor" json:"vendor"`         // Regular expression on the manufacturer name
+	Hostname  string   `yaml:"hostname" json:"hostname"`     // Regular expression on the resolved hostname
+	Ports     []int    `yaml:"ports" json:"ports"`           // TCP ports, any of which must be open
+}
+
+// RuleSet is an ordered collection of category rules, highest priority first
+type RuleSet struct {
+	rules []Rule
+}
+
+// LoadRules reads a rules file. YAML and JSON are both accepted:
+//
+//	rules:
+//	  - name: office-printers
+//	    category: Printer
+//	    priority: 100
+//	    match:
+//	      vendor: "(?i)brother|epson"
+//	      ports: [631, 9100]
+func LoadRules(path string) (*RuleSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rs, err := ParseRules(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rs, nil
+}
+
+// ParseRules parses and validates rules in YAML or JSON form. Unknown keys are
+// rejected, so a misspelled condition does not silently widen a rule.
+func ParseRules(raw []byte) (*RuleSet, error) {
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if r.Category == "" {
+			return nil, fmt.Errorf("%s: category is required", r.Name)
+		}
+		m := r.Match
+		if len(m.MACPrefix) == 0 && m.Vendor == "" && m.Hostname == "" && len(m.Ports) == 0 {
+			return nil, fmt.Errorf("%s: at least one match condition is required", r.Name)
+		}
+
+		for _, p := range m.MACPrefix {
+			prefix, err := normalizeRulePrefix(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Name, err)
+			}
+			r.prefixes = append(r.prefixes, prefix)
+		}
+		var err error
+		if m.Vendor != "" {
+			if r.vendor, err = regexp.Compile(m.Vendor); err != nil {
+				return nil, fmt.Errorf("%s: invalid vendor pattern: %w", r.Name, err)
+			}
+		}
+		if m.Hostname != "" {
+			if r.hostname, err = regexp.Compile(m.Hostname); err != nil {
+				return nil, fmt.Errorf("%s: invalid hostname pattern: %w", r.Name, err)
+			}
+		}
+		for _, port := range m.Ports {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("%s: invalid port %d", r.Name, port)
+			}
+		}
+	}
+
+	// Highest priority first; equal priorities keep their order in the file
+	sort.SliceStable(file.Rules, func(i, j int) bool {
+		return file.Rules[i].Priority > file.Rules[j].Priority
+	})
+	return &RuleSet{rules: file.Rules}, nil
+}
+
+// normalizeRulePrefix converts a MAC prefix in any notation into lower-case hex
+// digits, which may be an odd number long (e.g. a 28-bit block)
+func normalizeRulePrefix(prefix string) (string, error) {
+	digits := strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(prefix))
+	if digits == "" || len(digits) > 12 || strings.Trim(digits, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("invalid MAC prefix %q", prefix)
+	}
+	return digits, nil
+}
+
+// Ports returns every port some rule needs to check, sorted
+func (rs *RuleSet) Ports() []int {
+	seen := make(map[int]bool)
+	var ports []int
+	for _, r := range rs.rules {
+		for _, p := range r.Match.Ports {
+			if !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// Apply sets the category of a device from the highest-priority matching rule and
+// records the rule's name in CategoryRule. It reports whether a rule matched.
+func (rs *RuleSet) Apply(dev *Device) bool {
+	for i := range rs.rules {
+		r := &rs.rules[i]
+		if r.matches(dev) {
+			dev.Category = r.Category
+			dev.CategoryRule = r.Name
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a device satisfies every condition of the rule
+func (r *Rule) matches(dev *Device) bool {
+	if len(r.prefixes) > 0 {
+		mac, err := data.NormalizeMAC(dev.MAC)
+		if err != nil {
+			return false
+		}
+		digits := strings.ReplaceAll(mac, ":", "")
+		found := false
+		for _, p := range r.prefixes {
+			if strings.HasPrefix(digits, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.vendor != nil && !r.vendor.MatchString(dev.Manufacturer) {
+		return false
+	}
+	if r.hostname != nil && (dev.Hostname == "" || !r.hostname.MatchString(dev.Hostname)) {
+		return false
+	}
+	if len(r.Match.Ports) > 0 {
+		found := false
+		for _, want := range r.Match.Ports {
+			for _, open := range dev.OpenPorts {
+				if want == open {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
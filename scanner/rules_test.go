@@ -0,0 +1,171 @@
+package scanner
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+rules:
+  - name: lab-pis
+    category: Lab
+    priority: 50
+    match:
+      mac_prefix: ["B8-27-EB", "dca632"]
+      hostname: "^lab-"
+  - name: any-pi
+    category: Single Board Computer
+    priority: 10
+    match:
+      mac_prefix: ["b8:27:eb", "dc:a6:32"]
+  - name: office-printers
+    category: Printer
+    priority: 100
+    match:
+      vendor: "(?i)brother|epson"
+      ports: [631, 9100]
+  - name: sensors
+    category: Sensor
+    priority: 10
+    match:
+      mac_prefix: ["70:b3:d5:12:3"]
+  - category: Web
+    match:
+      ports: [80]
+`
+
+func TestRuleSetApply(t *testing.T) {
+	rs, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dev      Device
+		category string // "" when no rule matches
+		rule     string
+	}{
+		// Priority decides between two matching rules
+		{Device{MAC: "b8:27:eb:00:00:01", Hostname: "lab-3"}, "Lab", "lab-pis"},
+		{Device{MAC: "dc:a6:32:00:00:01", Hostname: "lab-4.local"}, "Lab", "lab-pis"},
+		{Device{MAC: "b8:27:eb:00:00:01", Hostname: "kitchen"}, "Single Board Computer", "any-pi"},
+		// A hostname condition never matches a device without a name
+		{Device{MAC: "b8:27:eb:00:00:01"}, "Single Board Computer", "any-pi"},
+		// Every condition must hold: the vendor alone is not enough
+		{Device{Manufacturer: "Brother Industries, Ltd.", OpenPorts: []int{9100}}, "Printer", "office-printers"},
+		{Device{Manufacturer: "Seiko Epson Corporation", OpenPorts: []int{22, 631}}, "Printer", "office-printers"},
+		{Device{Manufacturer: "Brother Industries, Ltd.", OpenPorts: []int{22}}, "", ""},
+		// Printers outrank the web rule; unnamed rules are numbered from 1
+		{Device{Manufacturer: "Brother Industries, Ltd.", OpenPorts: []int{80, 631}}, "Printer", "office-printers"},
+		{Device{Manufacturer: "HP", OpenPorts: []int{80}}, "Web", "rule 5"},
+		// 36-bit prefixes match on the partial fifth octet
+		{Device{MAC: "70:b3:d5:12:34:56"}, "Sensor", "sensors"},
+		{Device{MAC: "70:b3:d5:12:44:56"}, "", ""},
+		{Device{MAC: "not a mac"}, "", ""},
+	}
+	for _, tt := range tests {
+		dev := tt.dev
+		dev.Category = "Unknown"
+		matched := rs.Apply(&dev)
+		if tt.category == "" {
+			if matched || dev.Category != "Unknown" || dev.CategoryRule != "" {
+				t.Errorf("%+v: matched %q (%q), want no rule", tt.dev, dev.CategoryRule, dev.Category)
+			}
+			continue
+		}
+		if !matched || dev.Category != tt.category || dev.CategoryRule != tt.rule {
+			t.Errorf("%+v: category %q by %q, want %q by %q", tt.dev, dev.Category, dev.CategoryRule, tt.category, tt.rule)
+		}
+	}
+
+	if got := rs.Ports(); !reflect.DeepEqual(got, []int{80, 631, 9100}) {
+		t.Errorf("Ports() = %v", got)
+	}
+}
+
+func TestParseRulesJSON(t *testing.T) {
+	rs, err := ParseRules([]byte(`{"rules": [{"name": "nas", "category": "Storage", "match": {"vendor": "Synology"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := Device{Manufacturer: "Synology Incorporated"}
+	if !rs.Apply(&dev) || dev.Category != "Storage" || dev.CategoryRule != "nas" {
+		t.Errorf("device %+v", dev)
+	}
+}
+
+func TestParseRulesEmpty(t *testing.T) {
+	for _, raw := range []string{"", "rules: []\n", "# nothing yet\n"} {
+		rs, err := ParseRules([]byte(raw))
+		if err != nil {
+			t.Errorf("ParseRules(%q): %v", raw, err)
+			continue
+		}
+		if dev := (Device{MAC: "b8:27:eb:00:00:01"}); rs.Apply(&dev) {
+			t.Errorf("ParseRules(%q) matched %+v", raw, dev)
+		}
+	}
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // Substring of the error
+	}{
+		{"rules: [", "invalid rules file"},
+		{"rules:\n  - name: x\n   category: y\n", "invalid rules file"},
+		{"rules: {name: x}\n", "invalid rules file"},
+		{"rules:\n  - name: x\n    category: y\n    priority: high\n    match: {vendor: z}\n", "invalid rules file"},
+		// Unknown keys would silently drop a condition
+		{"rules:\n  - name: x\n    category: y\n    match: {vendor: z, hostnmae: w}\n", "field hostnmae not found"},
+		{"rules:\n  - name: x\n    categroy: y\n    match: {vendor: z}\n", "field categroy not found"},
+		{"rule:\n  - name: x\n", "field rule not found"},
+		{"rules:\n  - name: x\n    match: {vendor: z}\n", "x: category is required"},
+		{"rules:\n  - category: y\n", "rule 1: at least one match condition"},
+		{"rules:\n  - name: x\n    category: y\n    match: {mac_prefix: [b8:27:zz]}\n", "invalid MAC prefix"},
+		{"rules:\n  - name: x\n    category: y\n    match: {mac_prefix: ['b8:27:eb:12:34:56:78']}\n", "invalid MAC prefix"},
+		{"rules:\n  - name: x\n    category: y\n    match: {vendor: '('}\n", "invalid vendor pattern"},
+		{"rules:\n  - name: x\n    category: y\n    match: {hostname: '[a-'}\n", "invalid hostname pattern"},
+		{"rules:\n  - name: x\n    category: y\n    match: {ports: [0]}\n", "invalid port 0"},
+		{"rules:\n  - name: x\n    category: y\n    match: {ports: [65536]}\n", "invalid port 65536"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRules([]byte(tt.raw))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseRules(%q) error = %v, want %q", tt.raw, err, tt.want)
+		}
+	}
+}
+
+func TestScanAppliesRules(t *testing.T) {
+	rs, err := ParseRules([]byte("rules:\n  - name: lab\n    category: Lab\n    match: {hostname: '^lab-'}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(
+		WithProbers(fakeProber{
+			"10.0.0.1": {Alive: true, MAC: "b8:27:eb:00:00:01"},
+			"10.0.0.2": {Alive: true, MAC: "b8:27:eb:00:00:02"},
+		}),
+		WithResolvers(fakeResolver{"10.0.0.1": "lab-1", "10.0.0.2": "kitchen"}),
+		WithFingerprint(false),
+		WithRules(rs),
+	)
+	result, err := s.Scan(context.Background(), []string{"10.0.0.1", "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rules see the resolved names and override the OUI category
+	lab, kitchen := result.Devices[0], result.Devices[1]
+	if lab.Category != "Lab" || lab.CategoryRule != "lab" {
+		t.Errorf("matching device: category %q by %q", lab.Category, lab.CategoryRule)
+	}
+	if kitchen.Category != "Raspberry Pi" || kitchen.CategoryRule != "" {
+		t.Errorf("other device: category %q by %q", kitchen.Category, kitchen.CategoryRule)
+	}
+	if result.Categories["Lab"] != 1 {
+		t.Errorf("category statistics %v", result.Categories)
+	}
+}
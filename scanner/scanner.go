@@ -0,0 +1,498 @@
+// Package scanner discovers devices on local networks and identifies them by the
+// manufacturer of their network interface.
+//
+// A Scanner probes a list of IPv4 addresses (and optionally the IPv6 all-nodes group
+// on one interface), reads MAC addresses from the probe replies or the system neighbor
+// table, and looks each one up in the embedded OUI database:
+//
+//	ips, err := scanner.ExpandTargets("192.168.1.0/24", "", scanner.DefaultMaxHosts)
+//	if err != nil {
+//		return err
+//	}
+//	s := scanner.New(
+//		scanner.WithTimeout(time.Second),
+//		scanner.WithDeviceHandler(func(d scanner.Device) { fmt.Println(d.IP, d.Manufacturer) }),
+//	)
+//	result, err := s.Scan(ctx, ips)
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scanner runs network scans. Configure it with options passed to New; the zero
+// configuration pings each address once with a 500ms timeout, 32 probes per CPU.
+type Scanner struct {
+	timeout        time.Duration
+	pingCount      int
+	concurrency    int
+	resolve        bool
+	fingerprint    bool
+	prober         Prober
+	resolver       NameResolver
+	resolveWorkers int
+	resolveTimeout time.Duration
+	resolveTTL     time.Duration
+	ipv6Iface      string
+	mdns           bool
+	mdnsIface      string
+	ssdp           bool
+	ssdpIface      string
+	neighbors      neighborSource
+	rules          *RuleSet
+	leases         []Lease
+	leasesByMAC    map[string][]Lease
+	offline        bool
+	families       []DeviceFamily
+
+	onDevice   func(Device)
+	onProgress func(completed, total int)
+	onWarning  func(error)
+}
+
+// Option configures a Scanner
+type Option func(*Scanner)
+
+// WithTimeout sets how long the default ICMP prober and IPv6 discovery wait for replies
+func WithTimeout(d time.Duration) Option {
+	return func(s *Scanner) { s.timeout = d }
+}
+
+// WithPingCount sets how many echo requests are sent per host
+func WithPingCount(n int) Option {
+	return func(s *Scanner) { s.pingCount = n }
+}
+
+// WithConcurrency limits how many addresses are probed at once
+func WithConcurrency(n int) Option {
+	return func(s *Scanner) { s.concurrency = n }
+}
+
+// WithResolve enables or disables looking up the names of discovered devices
+func WithResolve(resolve bool) Option {
+	return func(s *Scanner) { s.resolve = resolve }
+}
+
+// WithFingerprint enables or disables fingerprinting, which queries each device over
+// mDNS and reads its SSH banner to recognize Raspberry Pis the OUI misses and to
+// estimate their model and operating system (Device.PiModel, Device.OS)
+func WithFingerprint(fingerprint bool) Option {
+	return func(s *Scanner) { s.fingerprint = fingerprint }
+}
+
+// WithProbers replaces the default ICMP prober. Probers are tried in order and the
+// first one to get an answer wins, as with ProbeChain.
+func WithProbers(probers ...Prober) Option {
+	return func(s *Scanner) {
+		if len(probers) == 1 {
+			s.prober = probers[0]
+			return
+		}
+		s.prober = ProbeChain(probers)
+	}
+}
+
+// WithResolvers replaces the default name resolvers, reverse DNS, NetBIOS and LLMNR.
+// All of them are asked at once and the earliest in the list that finds a name wins,
+// as with ResolverChain.
+func WithResolvers(resolvers ...NameResolver) Option {
+	return func(s *Scanner) {
+		if len(resolvers) == 1 {
+			s.resolver = resolvers[0]
+			return
+		}
+		s.resolver = ResolverChain(resolvers)
+	}
+}
+
+// WithResolveConcurrency limits how many devices are resolved at once, separately
+// from the probe limit
+func WithResolveConcurrency(n int) Option {
+	return func(s *Scanner) { s.resolveWorkers = n }
+}
+
+// WithResolveTimeout bounds the whole resolution stage of a scan. Devices not
+// resolved by then are left without a name.
+func WithResolveTimeout(d time.Duration) Option {
+	return func(s *Scanner) { s.resolveTimeout = d }
+}
+
+// WithResolveCacheTTL sets how long names are remembered between scans with the
+// same Scanner (DefaultResolveCacheTTL). Zero or less asks the resolvers every time.
+func WithResolveCacheTTL(d time.Duration) Option {
+	return func(s *Scanner) { s.resolveTTL = d }
+}
+
+// WithIPv6 also discovers IPv6 hosts by pinging the all-nodes multicast group on the
+// named interface
+func WithIPv6(ifaceName string) Option {
+	return func(s *Scanner) { s.ipv6Iface = ifaceName }
+}
+
+// WithMDNS browses for DNS-SD services during the scan by multicasting on the named
+// interface ("" for the system default) and attaches the .local names and services
+// it hears to the devices found (Device.MDNSName, Device.Services). The scan lasts at
+// least as long as the browse window, about 1.5s.
+func WithMDNS(ifaceName string) Option {
+	return func(s *Scanner) {
+		s.mdns = true
+		s.mdnsIface = ifaceName
+	}
+}
+
+// WithSSDP searches for UPnP devices during the scan by multicasting an SSDP M-SEARCH
+// on the named interface ("" for the system default), fetches the device description
+// of each responder and attaches it to the matching device (Device.UPnP). Like
+// WithMDNS, the scan lasts at least as long as the search window.
+func WithSSDP(ifaceName string) Option {
+	return func(s *Scanner) {
+		s.ssdp = true
+		s.ssdpIface = ifaceName
+	}
+}
+
+// WithRules assigns categories from user-defined rules, overriding the category from
+// the OUI database where a rule matches. Ports referenced by the rules are checked on
+// every identified device and recorded in Device.OpenPorts.
+func WithRules(rules *RuleSet) Option {
+	return func(s *Scanner) { s.rules = rules }
+}
+
+// WithLeases enriches devices with the DHCP leases given, matched by MAC address
+// (Device.Lease). Lease hostnames fill in names no resolver found. See ReadLeaseFile.
+func WithLeases(leases ...Lease) Option {
+	return func(s *Scanner) { s.leases = append(s.leases, leases...) }
+}
+
+// WithKnownOffline also reports devices holding an active lease on a scanned address
+// that did not answer, in ScanResult.Offline. It has no effect without WithLeases.
+func WithKnownOffline(enabled bool) Option {
+	return func(s *Scanner) { s.offline = enabled }
+}
+
+// WithDeviceFamilies replaces the device families devices are sorted into, which
+// default to DeviceFamilies. Append to DeviceFamilies to add a family of your own.
+func WithDeviceFamilies(families ...DeviceFamily) Option {
+	return func(s *Scanner) { s.families = families }
+}
+
+// WithDeviceHandler registers a function called for every identified device
+func WithDeviceHandler(fn func(Device)) Option {
+	return func(s *Scanner) { s.onDevice = fn }
+}
+
+// WithProgress registers a function called after each address has been probed
+func WithProgress(fn func(completed, total int)) Option {
+	return func(s *Scanner) { s.onProgress = fn }
+}
+
+// WithWarningHandler registers a function called for problems that do not stop the
+// scan, such as an unreadable neighbor table or failed IPv6 discovery
+func WithWarningHandler(fn func(error)) Option {
+	return func(s *Scanner) { s.onWarning = fn }
+}
+
+// New returns a Scanner configured with the given options
+func New(opts ...Option) *Scanner {
+	s := &Scanner{
+		timeout:        500 * time.Millisecond,
+		pingCount:      1,
+		resolve:        true,
+		resolveWorkers: 16,
+		resolveTimeout: 15 * time.Second,
+		resolveTTL:     DefaultResolveCacheTTL,
+		fingerprint:    true,
+		families:       DeviceFamilies,
+		neighbors:      defaultNeighborSource(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = runtime.NumCPU() * 32
+	}
+	if s.prober == nil {
+		s.prober = ICMPProber{Timeout: s.timeout, Count: s.pingCount}
+	}
+	if s.resolver == nil {
+		s.resolver = ResolverChain{
+			DNSResolver{Timeout: DefaultDNSTimeout},
+			NetBIOSResolver{Timeout: s.timeout},
+			LLMNRResolver{Timeout: s.timeout},
+		}
+	}
+	if s.resolveTTL > 0 {
+		s.resolver = NewResolverCache(s.resolver, s.resolveTTL)
+	}
+	s.leasesByMAC = make(map[string][]Lease)
+	for _, lease := range s.leases {
+		s.leasesByMAC[lease.MAC] = append(s.leasesByMAC[lease.MAC], lease)
+	}
+	return s
+}
+
+// Scan probes the given IPv4 addresses (see ExpandTargets), runs IPv6 discovery if
+// enabled, and identifies every host that answered. Callbacks may run on other
+// goroutines but never concurrently with each other. Cancelling ctx stops the scan
+// early and returns what was found so far.
+func (s *Scanner) Scan(ctx context.Context, targets []string) (ScanResult, error) {
+	if len(targets) == 0 && s.ipv6Iface == "" {
+		return ScanResult{}, errors.New("nothing to scan")
+	}
+
+	startTime := time.Now()
+	var devices []Device
+	heard := s.startListeners(ctx)
+
+	if len(targets) > 0 {
+		found := s.probeAll(ctx, targets)
+		identified, err := identifyDevices(s.neighbors, found, AddressFamilyIPv4)
+		if err != nil {
+			s.warn(fmt.Errorf("failed to read ARP table: %w", err))
+		}
+		s.enrich(ctx, identified, heard())
+		devices = append(devices, identified...)
+		s.report(identified)
+	}
+
+	if s.ipv6Iface != "" {
+		found, err := discoverIPv6(ctx, s.ipv6Iface, s.timeout, s.pingCount)
+		if err != nil {
+			s.warn(fmt.Errorf("IPv6 discovery failed: %w", err))
+		}
+		hosts := make([]liveHost, 0, len(found))
+		for _, ip := range found {
+			hosts = append(hosts, liveHost{IP: ip, Result: ProbeResult{Alive: true, Method: "icmpv6", Evidence: "ff02::1 echo reply"}})
+		}
+		identified, err := identifyDevices(s.neighbors, hosts, AddressFamilyIPv6)
+		if err != nil {
+			s.warn(fmt.Errorf("failed to read IPv6 neighbor cache: %w", err))
+		}
+		s.enrich(ctx, identified, heard())
+		devices = append(devices, identified...)
+		s.report(identified)
+	}
+
+	manufacturerStats, categoryStats := Statistics(devices)
+	result := ScanResult{
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Network:      s.networkLabel(targets),
+		Duration:     time.Since(startTime).Seconds(),
+		TotalDevices: len(devices),
+		Devices:      devices,
+		Statistics:   manufacturerStats,
+		Categories:   categoryStats,
+		Families:     familyStatistics(devices),
+	}
+	if s.offline && len(s.leases) > 0 {
+		result.Offline = s.knownOffline(targets, devices)
+	}
+	result.PiCount = len(result.RaspberryPis())
+	return result, nil
+}
+
+// liveHost is an address that answered a probe, with what the probe learned about it
+type liveHost struct {
+	IP     string
+	Result ProbeResult
+}
+
+// probeAll probes addresses concurrently with proper goroutine management
+func (s *Scanner) probeAll(ctx context.Context, ips []string) []liveHost {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		found     []liveHost
+		semaphore = make(chan struct{}, s.concurrency)
+		completed = 0
+		total     = len(ips)
+	)
+
+	for _, ip := range ips {
+		wg.Add(1)
+		semaphore <- struct{}{} // Acquire semaphore
+
+		go func(ipAddr string) {
+			defer wg.Done()
+			defer func() { <-semaphore }() // Release semaphore
+
+			result := s.prober.Probe(ctx, ipAddr)
+
+			mu.Lock()
+			if result.Alive {
+				found = append(found, liveHost{IP: ipAddr, Result: result})
+			}
+			completed++
+			if s.onProgress != nil {
+				s.onProgress(completed, total)
+			}
+			mu.Unlock()
+		}(ip)
+	}
+
+	wg.Wait()
+	close(semaphore)
+	return found
+}
+
+// announcements is what devices said about themselves while the scan ran, keyed by
+// address without zone
+type announcements struct {
+	mdns map[string]mdnsHost
+	upnp map[string]UPnP
+}
+
+// startListeners starts mDNS browsing and SSDP discovery in the background, as
+// enabled. The returned function waits for both to finish and returns the results.
+func (s *Scanner) startListeners(ctx context.Context) func() announcements {
+	// SSDP responders answer within the one second the M-SEARCH allows (MX: 1), so
+	// the mDNS window is long enough for both
+	window := max(mdnsMinWindow, 2*s.timeout)
+	var (
+		wg               sync.WaitGroup
+		heard            announcements
+		mdnsErr, ssdpErr error
+	)
+	if s.mdns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, window)
+			defer cancel()
+			heard.mdns, mdnsErr = browseMDNS(ctx, s.mdnsIface, s.ipv6Iface)
+		}()
+	}
+	if s.ssdp {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			heard.upnp, ssdpErr = browseSSDP(ctx, window, s.ssdpIface)
+		}()
+	}
+	return sync.OnceValue(func() announcements {
+		wg.Wait()
+		if mdnsErr != nil {
+			s.warn(fmt.Errorf("mDNS browsing failed: %w", mdnsErr))
+		}
+		if ssdpErr != nil {
+			s.warn(fmt.Errorf("SSDP discovery failed: %w", ssdpErr))
+		}
+		return heard
+	})
+}
+
+// enrich adds everything learned beyond the probe to identified devices: names,
+// DHCP leases, mDNS services, UPnP descriptions, fingerprints, device families and rule-based
+// categories
+func (s *Scanner) enrich(ctx context.Context, devices []Device, heard announcements) {
+	if s.resolve {
+		s.resolveNames(ctx, devices)
+	}
+	attachLeases(devices, s.leasesByMAC)
+	attachMDNS(devices, heard.mdns)
+	attachSSDP(devices, heard.upnp)
+	if s.fingerprint {
+		s.fingerprintAll(ctx, devices)
+	}
+	detectFamilies(s.families, devices)
+	s.classify(ctx, devices)
+}
+
+// resolveNames looks up the names of devices with a pool of its own, giving up on
+// the rest when the resolution deadline passes
+func (s *Scanner) resolveNames(ctx context.Context, devices []Device) {
+	resolveCtx, cancel := context.WithTimeout(ctx, s.resolveTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, max(s.resolveWorkers, 1))
+dispatch:
+	for i := range devices {
+		select {
+		case semaphore <- struct{}{}:
+		case <-resolveCtx.Done():
+			break dispatch
+		}
+		wg.Add(1)
+		go func(dev *Device) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			result := s.resolver.Resolve(resolveCtx, dev.IP)
+			if result.Name != "" && result.Source == "" {
+				result.Source = s.resolver.Name()
+			}
+			dev.Hostname, dev.HostnameSource = result.Name, result.Source
+			dev.Workgroup = result.Workgroup
+		}(&devices[i])
+	}
+	wg.Wait()
+
+	if resolveCtx.Err() != nil && ctx.Err() == nil {
+		s.warn(fmt.Errorf("hostname resolution stopped after %v; some devices have no name", s.resolveTimeout))
+	}
+}
+
+// classify applies the category rules, first checking the ports they refer to
+func (s *Scanner) classify(ctx context.Context, devices []Device) {
+	if s.rules == nil {
+		return
+	}
+
+	if ports := s.rules.Ports(); len(ports) > 0 {
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, s.concurrency)
+		for i := range devices {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(dev *Device) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				dev.OpenPorts = openPorts(ctx, dev.IP, ports, s.timeout)
+			}(&devices[i])
+		}
+		wg.Wait()
+	}
+
+	for i := range devices {
+		s.rules.Apply(&devices[i])
+	}
+}
+
+// report passes identified devices to the device handler
+func (s *Scanner) report(devices []Device) {
+	if s.onDevice == nil {
+		return
+	}
+	for _, dev := range devices {
+		s.onDevice(dev)
+	}
+}
+
+// warn passes a non-fatal error to the warning handler
+func (s *Scanner) warn(err error) {
+	if s.onWarning != nil {
+		s.onWarning(err)
+	}
+}
+
+// networkLabel describes what was scanned, e.g. "10.0.0.1-10.0.0.254,ff02::1%eth0"
+func (s *Scanner) networkLabel(targets []string) string {
+	var labels []string
+	switch len(targets) {
+	case 0:
+	case 1:
+		labels = append(labels, targets[0])
+	default:
+		labels = append(labels, targets[0]+"-"+targets[len(targets)-1])
+	}
+	if s.ipv6Iface != "" {
+		labels = append(labels, allNodesMulticast.String()+"%"+s.ipv6Iface)
+	}
+	return strings.Join(labels, ",")
+}
@@ -0,0 +1,171 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/james-see/gofindpi/data"
+)
+
+// fakeProber answers for the addresses in its table
+type fakeProber map[string]ProbeResult
+
+func (p fakeProber) Name() string { return "fake" }
+
+func (p fakeProber) Probe(ctx context.Context, ip string) ProbeResult {
+	return p[ip]
+}
+
+// fakeResolver names addresses from its table. Addresses named "slow" block until
+// the lookup is cancelled.
+type fakeResolver map[string]string
+
+func (r fakeResolver) Name() string { return "fake" }
+
+func (r fakeResolver) Resolve(ctx context.Context, ip string) NameResult {
+	if r[ip] == "slow" {
+		<-ctx.Done()
+		return NameResult{}
+	}
+	return NameResult{Name: r[ip]}
+}
+
+// fakeNeighbors is a neighbor table that may also fail
+type fakeNeighbors struct {
+	neighbors []neighbor
+	err       error
+}
+
+func (n fakeNeighbors) Neighbors(family string) ([]neighbor, error) {
+	return n.neighbors, n.err
+}
+
+func TestScan(t *testing.T) {
+	prober := fakeProber{
+		"10.0.0.10": {Alive: true, RTT: 1500 * time.Microsecond, MAC: "b8:27:eb:00:00:10", Method: "fake", Evidence: "reply"},
+		"10.0.0.9":  {Alive: true, Method: "fake", Evidence: "reply"},
+		"10.0.0.2":  {Alive: true, Method: "fake", Evidence: "reply"},
+		"10.0.0.3":  {Alive: true, Method: "fake", Evidence: "reply"}, // In no neighbor table
+	}
+	resolver := fakeResolver{"10.0.0.10": "raspberrypi.lan", "10.0.0.9": "phone.lan"}
+	var (
+		progress []int
+		reported []string
+		warnings []error
+	)
+	s := New(
+		WithProbers(prober),
+		WithResolvers(resolver),
+		WithFingerprint(false),
+		WithConcurrency(2),
+		WithProgress(func(completed, total int) {
+			if total != 6 {
+				t.Errorf("progress total %d, want 6", total)
+			}
+			progress = append(progress, completed)
+		}),
+		WithDeviceHandler(func(d Device) { reported = append(reported, d.IP) }),
+		WithWarningHandler(func(err error) { warnings = append(warnings, err) }),
+	)
+	// The table is only partly readable; what it returns is still used
+	s.neighbors = fakeNeighbors{
+		neighbors: []neighbor{
+			{IP: "10.0.0.9", MAC: "06:11:22:33:44:55"},
+			{IP: "10.0.0.2", MAC: "00:00:0c:01:02:03"},
+		},
+		err: errors.New("permission denied"),
+	}
+
+	result, err := s.Scan(context.Background(), []string{"10.0.0.10", "10.0.0.9", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sorted by address, not as strings
+	wantIPs := []string{"10.0.0.2", "10.0.0.9", "10.0.0.10"}
+	var ips []string
+	for _, dev := range result.Devices {
+		ips = append(ips, dev.IP)
+	}
+	if !reflect.DeepEqual(ips, wantIPs) {
+		t.Fatalf("devices %v, want %v", ips, wantIPs)
+	}
+	if !reflect.DeepEqual(reported, wantIPs) {
+		t.Errorf("device handler saw %v, want %v", reported, wantIPs)
+	}
+
+	cisco, phone, pi := result.Devices[0], result.Devices[1], result.Devices[2]
+	if cisco.MAC != "00:00:0c:01:02:03" || cisco.Manufacturer != "Cisco Systems, Inc" || cisco.Hostname != "" {
+		t.Errorf("device from the neighbor table: %+v", cisco)
+	}
+	if phone.Category != data.CategoryRandomized || !phone.LocallyAdministered || phone.Hostname != "phone.lan" || phone.HostnameSource != "fake" {
+		t.Errorf("randomized device: %+v", phone)
+	}
+	want := Device{
+		IP:             "10.0.0.10",
+		MAC:            "b8:27:eb:00:00:10",
+		Manufacturer:   pi.Manufacturer,
+		Category:       "Raspberry Pi",
+		IsRaspberryPi:  true,
+		DeviceFamily:   FamilyRaspberryPi,
+		Hostname:       "raspberrypi.lan",
+		HostnameSource: "fake",
+		AddressFamily:  AddressFamilyIPv4,
+		DiscoveredBy:   "fake",
+		Evidence:       "reply",
+		RTTMillis:      1.5,
+	}
+	if !reflect.DeepEqual(pi, want) {
+		t.Errorf("device with a MAC from the probe:\n got %+v\nwant %+v", pi, want)
+	}
+
+	if result.TotalDevices != 3 || result.PiCount != 1 || result.Network != "10.0.0.10-10.0.0.5" {
+		t.Errorf("result totals %d devices, %d Pis, network %q", result.TotalDevices, result.PiCount, result.Network)
+	}
+	if result.Categories["Raspberry Pi"] != 1 || result.Families[FamilyRaspberryPi] != 1 {
+		t.Errorf("statistics %v, families %v", result.Categories, result.Families)
+	}
+
+	// Progress counts every address once, in order
+	if !reflect.DeepEqual(progress, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("progress %v", progress)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "ARP table: permission denied") {
+		t.Errorf("warnings %v", warnings)
+	}
+}
+
+func TestScanResolveTimeout(t *testing.T) {
+	var warnings []error
+	s := New(
+		WithProbers(fakeProber{
+			"10.0.0.1": {Alive: true, MAC: "b8:27:eb:00:00:01"},
+			"10.0.0.2": {Alive: true, MAC: "b8:27:eb:00:00:02"},
+		}),
+		WithResolvers(fakeResolver{"10.0.0.1": "slow", "10.0.0.2": "pi2.lan"}),
+		WithResolveTimeout(50*time.Millisecond),
+		WithFingerprint(false),
+		WithWarningHandler(func(err error) { warnings = append(warnings, err) }),
+	)
+
+	result, err := s.Scan(context.Background(), []string{"10.0.0.1", "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Devices) != 2 || result.Devices[0].Hostname != "" || result.Devices[1].Hostname != "pi2.lan" {
+		t.Errorf("devices %+v", result.Devices)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "hostname resolution stopped") {
+		t.Errorf("warnings %v", warnings)
+	}
+}
+
+func TestScanNothing(t *testing.T) {
+	if _, err := New().Scan(context.Background(), nil); err == nil {
+		t.Error("a scan without targets succeeded")
+	}
+}
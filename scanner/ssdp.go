@@ -0,0 +1,289 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-see/gofindpi/data"
+	"golang.org/x/net/ipv4"
+)
+
+// ssdpGroup is the SSDP multicast address (UPnP Device Architecture 1.1, section 1)
+var ssdpGroup = netip.AddrPortFrom(netip.MustParseAddr("239.255.255.250"), 1900)
+
+// descriptionTimeout bounds fetching all UPnP device descriptions after the window
+const descriptionTimeout = 2 * time.Second
+
+// maxDescriptionSize caps how much of a device description is read
+const maxDescriptionSize = 1 << 20
+
+// UPnP identifies a device from its SSDP response and UPnP device description
+type UPnP struct {
+	FriendlyName string `json:"friendly_name,omitempty"` // e.g. "Living Room TV"
+	Manufacturer string `json:"manufacturer,omitempty"`
+	ModelName    string `json:"model_name,omitempty"`
+	ModelNumber  string `json:"model_number,omitempty"`
+	DeviceType   string `json:"device_type,omitempty"` // e.g. "urn:schemas-upnp-org:device:MediaRenderer:1"
+	Server       string `json:"server,omitempty"`      // SERVER header, e.g. "Linux/5.4 UPnP/1.0 MiniUPnPd/2.2"
+	Location     string `json:"location,omitempty"`    // URL of the device description
+}
+
+// upnpCategories maps UPnP device types to the categories used by the OUI database
+var upnpCategories = map[string]string{
+	"InternetGatewayDevice": "Network Equipment",
+	"WANDevice":             "Network Equipment",
+	"WLANAccessPointDevice": "Network Equipment",
+	"MediaRenderer":         "TV/Streaming",
+	"dial":                  "TV/Streaming",
+	"MediaServer":           "Storage/NAS",
+	"ZonePlayer":            "IoT/Audio",
+	"Printer":               "Printer",
+}
+
+// ssdpResponse is one answer to an M-SEARCH
+type ssdpResponse struct {
+	Location string
+	Server   string
+}
+
+// ssdpConn is a socket M-SEARCH requests are sent through, with the address to
+// send them to
+type ssdpConn struct {
+	conn  net.PacketConn
+	group net.Addr
+}
+
+// openSSDPConn opens a socket that multicasts on the named interface, or the system
+// default if ifaceName is empty
+func openSSDPConn(ifaceName string) (ssdpConn, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return ssdpConn{}, err
+	}
+	if ifaceName != "" {
+		if ifi, err := net.InterfaceByName(ifaceName); err == nil {
+			ipv4.NewPacketConn(conn).SetMulticastInterface(ifi)
+		}
+	}
+	return ssdpConn{conn, net.UDPAddrFromAddrPort(ssdpGroup)}, nil
+}
+
+// searchSSDP sends an M-SEARCH for all devices and services and collects the
+// responses until ctx is done, keyed by responder address. It closes the connection.
+func searchSSDP(ctx context.Context, c ssdpConn) map[string][]ssdpResponse {
+	request := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpGroup.String() + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: ssdp:all\r\n\r\n"
+
+	var (
+		mu        sync.Mutex
+		responses = make(map[string][]ssdpResponse)
+		done      = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := c.conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			resp, ok := parseSSDPResponse(buf[:n])
+			udp, isUDP := from.(*net.UDPAddr)
+			if !ok || !isUDP {
+				continue
+			}
+			addr, _ := netip.AddrFromSlice(udp.IP)
+			ip := addr.Unmap().String()
+
+			mu.Lock()
+			known := false
+			for _, r := range responses[ip] {
+				known = known || r.Location == resp.Location
+			}
+			if !known {
+				responses[ip] = append(responses[ip], resp)
+			}
+			mu.Unlock()
+		}
+	}()
+
+	// UDP is unreliable, so send the search twice as the specification recommends
+	c.conn.WriteTo([]byte(request), c.group)
+	select {
+	case <-time.After(100 * time.Millisecond):
+		c.conn.WriteTo([]byte(request), c.group)
+	case <-ctx.Done():
+	}
+
+	<-ctx.Done()
+	c.conn.Close()
+	<-done
+	return responses
+}
+
+// parseSSDPResponse parses an HTTP-over-UDP answer to an M-SEARCH:
+//
+//	HTTP/1.1 200 OK
+//	LOCATION: http://192.168.1.1:5000/rootDesc.xml
+//	SERVER: Linux/5.4 UPnP/1.0 MiniUPnPd/2.2
+//	ST: upnp:rootdevice
+func parseSSDPResponse(packet []byte) (ssdpResponse, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(packet)), nil)
+	if err != nil {
+		return ssdpResponse{}, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ssdpResponse{}, false
+	}
+	return ssdpResponse{
+		Location: resp.Header.Get("Location"),
+		Server:   resp.Header.Get("Server"),
+	}, true
+}
+
+// upnpDescription is the part of a UPnP device description gofindpi uses
+type upnpDescription struct {
+	Device struct {
+		DeviceType   string `xml:"deviceType"`
+		FriendlyName string `xml:"friendlyName"`
+		Manufacturer string `xml:"manufacturer"`
+		ModelName    string `xml:"modelName"`
+		ModelNumber  string `xml:"modelNumber"`
+	} `xml:"device"`
+}
+
+// fetchDescription downloads and parses a UPnP device description. Only URLs on the
+// responder itself are followed, so a device cannot point the scanner elsewhere.
+func fetchDescription(ctx context.Context, client *http.Client, ip, location string) (upnpDescription, error) {
+	var desc upnpDescription
+	u, err := url.Parse(location)
+	if err != nil {
+		return desc, err
+	}
+	if u.Scheme != "http" || u.Hostname() != ip {
+		return desc, fmt.Errorf("description %s is not served by %s", location, ip)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return desc, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return desc, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return desc, fmt.Errorf("%s: HTTP %d", location, resp.StatusCode)
+	}
+
+	err = xml.NewDecoder(io.LimitReader(resp.Body, maxDescriptionSize)).Decode(&desc)
+	return desc, err
+}
+
+// describeSSDP fetches the device description of every responder, trying its
+// locations in the order they were announced until one parses
+func describeSSDP(ctx context.Context, responses map[string][]ssdpResponse) map[string]UPnP {
+	ctx, cancel := context.WithTimeout(ctx, descriptionTimeout)
+	defer cancel()
+	client := &http.Client{
+		// Descriptions are served directly; redirects could lead off the device
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		devices = make(map[string]UPnP)
+	)
+	for ip, answers := range responses {
+		wg.Add(1)
+		go func(ip string, answers []ssdpResponse) {
+			defer wg.Done()
+			info := UPnP{Server: answers[0].Server, Location: answers[0].Location}
+			for _, answer := range answers {
+				desc, err := fetchDescription(ctx, client, ip, answer.Location)
+				if err != nil {
+					continue
+				}
+				d := desc.Device
+				info = UPnP{
+					FriendlyName: strings.TrimSpace(d.FriendlyName),
+					Manufacturer: strings.TrimSpace(d.Manufacturer),
+					ModelName:    strings.TrimSpace(d.ModelName),
+					ModelNumber:  strings.TrimSpace(d.ModelNumber),
+					DeviceType:   strings.TrimSpace(d.DeviceType),
+					Server:       answer.Server,
+					Location:     answer.Location,
+				}
+				break
+			}
+			mu.Lock()
+			devices[ip] = info
+			mu.Unlock()
+		}(ip, answers)
+	}
+	wg.Wait()
+	return devices
+}
+
+// browseSSDP searches for UPnP devices for the length of window and then fetches
+// their descriptions, keyed by address
+func browseSSDP(ctx context.Context, window time.Duration, ifaceName string) (map[string]UPnP, error) {
+	c, err := openSSDPConn(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	searchCtx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+	return describeSSDP(ctx, searchSSDP(searchCtx, c)), nil
+}
+
+// upnpCategory returns the category for a UPnP device type such as
+// "urn:schemas-upnp-org:device:MediaRenderer:1"
+func upnpCategory(deviceType string) (string, bool) {
+	parts := strings.Split(deviceType, ":")
+	if len(parts) < 2 {
+		return "", false
+	}
+	category, ok := upnpCategories[parts[len(parts)-2]]
+	return category, ok
+}
+
+// attachSSDP copies UPnP identities onto the matching devices. They fill in the
+// manufacturer and category where the OUI database knew nothing, which is common
+// for TVs and media players behind randomized or white-label network chips.
+func attachSSDP(devices []Device, upnp map[string]UPnP) {
+	for i := range devices {
+		dev := &devices[i]
+		ip, _, _ := strings.Cut(dev.IP, "%")
+		info, ok := upnp[ip]
+		if !ok {
+			continue
+		}
+		dev.UPnP = &info
+		if dev.Manufacturer == "Unknown" && info.Manufacturer != "" {
+			dev.Manufacturer = info.Manufacturer
+		}
+		if dev.Category == "Unknown" || dev.Category == data.CategoryRandomized {
+			if category, ok := upnpCategory(info.DeviceType); ok {
+				dev.Category = category
+			}
+		}
+	}
+}
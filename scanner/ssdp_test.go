@@ -0,0 +1,204 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName> Living Room TV </friendlyName>
+    <manufacturer>Example Electronics</manufacturer>
+    <modelName>ExampleTV</modelName>
+    <modelNumber>55X</modelNumber>
+  </device>
+</root>`
+
+// descriptionServer serves testDescription at /desc.xml and a redirect to it at
+// /moved
+func descriptionServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/desc.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(testDescription))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/desc.xml", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParseSSDPResponse(t *testing.T) {
+	tests := []struct {
+		packet string
+		want   ssdpResponse
+		ok     bool
+	}{
+		{
+			packet: "HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=120\r\nLOCATION: http://192.168.1.1:5000/rootDesc.xml\r\n" +
+				"SERVER: Linux/5.4 UPnP/1.0 MiniUPnPd/2.2\r\nST: upnp:rootdevice\r\nEXT:\r\n\r\n",
+			want: ssdpResponse{Location: "http://192.168.1.1:5000/rootDesc.xml", Server: "Linux/5.4 UPnP/1.0 MiniUPnPd/2.2"},
+			ok:   true,
+		},
+		{
+			packet: "HTTP/1.1 200 OK\r\nlocation: http://192.168.1.20:49152/description.xml\r\n\r\n",
+			want:   ssdpResponse{Location: "http://192.168.1.20:49152/description.xml"},
+			ok:     true,
+		},
+		{packet: "HTTP/1.1 404 Not Found\r\n\r\n"},
+		{packet: "NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nNTS: ssdp:alive\r\n\r\n"},
+		{packet: "\x00\x01garbage"},
+	}
+	for _, tt := range tests {
+		got, ok := parseSSDPResponse([]byte(tt.packet))
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseSSDPResponse(%q) = %+v, %v; want %+v, %v", tt.packet, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSearchSSDP(t *testing.T) {
+	// The fake responder stands in for the multicast group
+	responder, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responder.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := responder.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if !bytes.HasPrefix(buf[:n], []byte("M-SEARCH * HTTP/1.1\r\n")) || !bytes.Contains(buf[:n], []byte("ST: ssdp:all")) {
+				continue
+			}
+			// One answer per service, as real devices send
+			for _, st := range []string{"upnp:rootdevice", "urn:schemas-upnp-org:service:AVTransport:1"} {
+				responder.WriteTo([]byte("HTTP/1.1 200 OK\r\nLOCATION: http://127.0.0.1:8008/desc.xml\r\nSERVER: Test/1.0\r\nST: "+st+"\r\n\r\n"), from)
+			}
+		}
+	}()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	got := searchSSDP(ctx, ssdpConn{conn, responder.LocalAddr()})
+
+	want := map[string][]ssdpResponse{
+		"127.0.0.1": {{Location: "http://127.0.0.1:8008/desc.xml", Server: "Test/1.0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("searchSSDP() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFetchDescription(t *testing.T) {
+	srv := descriptionServer(t)
+	client := &http.Client{Timeout: time.Second}
+
+	desc, err := fetchDescription(context.Background(), client, "127.0.0.1", srv.URL+"/desc.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := desc.Device; d.FriendlyName != " Living Room TV " || d.Manufacturer != "Example Electronics" ||
+		d.ModelName != "ExampleTV" || d.ModelNumber != "55X" || d.DeviceType != "urn:schemas-upnp-org:device:MediaRenderer:1" {
+		t.Errorf("description %+v", d)
+	}
+
+	// Locations that are not plain HTTP on the responder itself are never requested
+	for _, location := range []string{
+		strings.Replace(srv.URL, "127.0.0.1", "localhost", 1) + "/desc.xml",
+		"http://192.0.2.10/desc.xml",
+		strings.Replace(srv.URL, "http:", "https:", 1) + "/desc.xml",
+		"file:///etc/passwd",
+	} {
+		if _, err := fetchDescription(context.Background(), client, "127.0.0.1", location); err == nil || !strings.Contains(err.Error(), "is not served by") {
+			t.Errorf("fetchDescription(%q) error = %v, want an off-host rejection", location, err)
+		}
+	}
+
+	if _, err := fetchDescription(context.Background(), client, "127.0.0.1", srv.URL+"/missing.xml"); err == nil {
+		t.Error("a 404 description was accepted")
+	}
+}
+
+func TestDescribeSSDP(t *testing.T) {
+	srv := descriptionServer(t)
+
+	got := describeSSDP(context.Background(), map[string][]ssdpResponse{
+		// The off-host location is skipped in favor of the responder's own
+		"127.0.0.1": {
+			{Location: "http://192.0.2.10/desc.xml", Server: "Evil/1.0"},
+			{Location: srv.URL + "/desc.xml", Server: "Test/1.0"},
+		},
+		// Redirects are not followed, so only the SSDP response is known
+		"127.0.0.2": {{Location: strings.Replace(srv.URL, "127.0.0.1", "127.0.0.2", 1) + "/moved", Server: "Moved/1.0"}},
+	})
+
+	want := map[string]UPnP{
+		"127.0.0.1": {
+			FriendlyName: "Living Room TV",
+			Manufacturer: "Example Electronics",
+			ModelName:    "ExampleTV",
+			ModelNumber:  "55X",
+			DeviceType:   "urn:schemas-upnp-org:device:MediaRenderer:1",
+			Server:       "Test/1.0",
+			Location:     srv.URL + "/desc.xml",
+		},
+		"127.0.0.2": {
+			Server:   "Moved/1.0",
+			Location: strings.Replace(srv.URL, "127.0.0.1", "127.0.0.2", 1) + "/moved",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("describeSSDP() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestAttachSSDP(t *testing.T) {
+	upnp := map[string]UPnP{
+		"192.168.1.20": {Manufacturer: "Example Electronics", DeviceType: "urn:schemas-upnp-org:device:MediaRenderer:1"},
+		"192.168.1.1":  {Manufacturer: "Router Maker", DeviceType: "urn:schemas-upnp-org:device:InternetGatewayDevice:1"},
+		"fe80::5":      {Manufacturer: "Speaker Co", DeviceType: "urn:schemas-sonos-com:device:ZonePlayer:1"},
+	}
+	devices := []Device{
+		{IP: "192.168.1.20", Manufacturer: "Unknown", Category: "Unknown"},
+		{IP: "192.168.1.1", Manufacturer: "Netgear", Category: "Network Equipment"},
+		{IP: "fe80::5%eth0", Manufacturer: "Unknown", Category: "Randomized MAC"},
+		{IP: "192.168.1.99", Manufacturer: "Unknown", Category: "Unknown"},
+	}
+	attachSSDP(devices, upnp)
+
+	want := []struct{ manufacturer, category string }{
+		{"Example Electronics", "TV/Streaming"},
+		{"Netgear", "Network Equipment"}, // The OUI database wins
+		{"Speaker Co", "IoT/Audio"},
+		{"Unknown", "Unknown"},
+	}
+	for i, dev := range devices {
+		if dev.Manufacturer != want[i].manufacturer || dev.Category != want[i].category {
+			t.Errorf("%s: %q/%q, want %q/%q", dev.IP, dev.Manufacturer, dev.Category, want[i].manufacturer, want[i].category)
+		}
+		if (dev.UPnP != nil) != (i < 3) {
+			t.Errorf("%s: UPnP = %+v", dev.IP, dev.UPnP)
+		}
+	}
+}
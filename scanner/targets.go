@@ -0,0 +1,231 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxHosts caps how many addresses a single scan may expand to
+const DefaultMaxHosts = 65536
+
+// targetRange is an inclusive range of IPv4 addresses
+type targetRange struct {
+	first netip.Addr
+	last  netip.Addr
+}
+
+// contains reports whether addr falls inside the range
+func (r targetRange) contains(addr netip.Addr) bool {
+	return r.first.Compare(addr) <= 0 && addr.Compare(r.last) <= 0
+}
+
+// size returns the number of addresses in the range
+func (r targetRange) size() uint64 {
+	return uint64(ipv4ToUint(r.last)) - uint64(ipv4ToUint(r.first)) + 1
+}
+
+// ipv4ToUint converts an IPv4 address to its 32-bit integer form
+func ipv4ToUint(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// uintToIPv4 converts a 32-bit integer back into an IPv4 address
+func uintToIPv4(v uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+}
+
+// prefixRange returns the addresses covered by a prefix. With hostsOnly set, the
+// network and broadcast addresses are dropped for prefixes that have them (/30 and larger).
+func prefixRange(p netip.Prefix, hostsOnly bool) targetRange {
+	p = p.Masked()
+	first := ipv4ToUint(p.Addr())
+	last := first | (1<<(32-p.Bits()) - 1)
+	if hostsOnly && p.Bits() <= 30 {
+		first++
+		last--
+	}
+	return targetRange{first: uintToIPv4(first), last: uintToIPv4(last)}
+}
+
+// parseTargetSpec parses a comma-separated target specification. Each term may be
+// a single address (10.0.0.5), a CIDR block (10.0.0.0/22), a range (10.0.0.10-10.0.0.80
+// or 10.0.0.10-80) or @path to read further terms from a file.
+func parseTargetSpec(spec string, hostsOnly bool) ([]targetRange, error) {
+	var ranges []targetRange
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		if strings.HasPrefix(term, "@") {
+			fileRanges, err := readTargetFile(term[1:], hostsOnly)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, fileRanges...)
+			continue
+		}
+
+		r, err := parseTargetTerm(term, hostsOnly)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// parseTargetTerm parses a single address, CIDR block or range
+func parseTargetTerm(term string, hostsOnly bool) (targetRange, error) {
+	if strings.Contains(term, "/") {
+		prefix, err := netip.ParsePrefix(term)
+		if err != nil {
+			return targetRange{}, fmt.Errorf("invalid CIDR %q: %w", term, err)
+		}
+		if !prefix.Addr().Is4() {
+			return targetRange{}, fmt.Errorf("invalid CIDR %q: only IPv4 targets are supported", term)
+		}
+		return prefixRange(prefix, hostsOnly), nil
+	}
+
+	if from, to, ok := strings.Cut(term, "-"); ok {
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		first, err := parseIPv4(from)
+		if err != nil {
+			return targetRange{}, fmt.Errorf("invalid range %q: %w", term, err)
+		}
+		if !strings.Contains(to, ".") {
+			// Shorthand: 10.0.0.10-80 replaces only the last octet
+			to = from[:strings.LastIndex(from, ".")+1] + to
+		}
+		last, err := parseIPv4(to)
+		if err != nil {
+			return targetRange{}, fmt.Errorf("invalid range %q: %w", term, err)
+		}
+		if last.Less(first) {
+			return targetRange{}, fmt.Errorf("invalid range %q: end is before start", term)
+		}
+		return targetRange{first: first, last: last}, nil
+	}
+
+	addr, err := parseIPv4(term)
+	if err != nil {
+		return targetRange{}, err
+	}
+	return targetRange{first: addr, last: addr}, nil
+}
+
+// parseIPv4 parses a dotted-quad IPv4 address
+func parseIPv4(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return netip.Addr{}, fmt.Errorf("invalid address %q: only IPv4 targets are supported", s)
+	}
+	return addr, nil
+}
+
+// readTargetFile reads target terms from a file, one or more per line. Blank lines
+// and anything after a # are ignored.
+func readTargetFile(path string, hostsOnly bool) ([]targetRange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open target file: %w", err)
+	}
+	defer file.Close()
+
+	var ranges []targetRange
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, term := range strings.Fields(strings.ReplaceAll(line, ",", " ")) {
+			if strings.HasPrefix(term, "@") {
+				return nil, fmt.Errorf("%s:%d: nested target files are not supported", path, lineNum)
+			}
+			r, err := parseTargetTerm(term, hostsOnly)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading target file: %w", err)
+	}
+	return ranges, nil
+}
+
+// ExpandTargets parses a target specification and an exclusion list in the same syntax
+// and returns the sorted, de-duplicated addresses to scan. A maxHosts of 0 means no limit.
+//
+// Targets may be single addresses (10.0.0.5), CIDR blocks (10.0.0.0/22), ranges
+// (10.0.0.10-10.0.0.80 or 10.0.0.10-80) or @path to read terms from a file, separated
+// by commas. Network and broadcast addresses of CIDR targets are skipped.
+func ExpandTargets(spec, exclude string, maxHosts int) ([]string, error) {
+	include, err := parseTargetSpec(spec, true)
+	if err != nil {
+		return nil, err
+	}
+	// Exclusions are taken literally so an excluded CIDR also covers its network/broadcast
+	skip, err := parseTargetSpec(exclude, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclusion: %w", err)
+	}
+	return expandTargets(include, skip, maxHosts)
+}
+
+// expandTargets turns target ranges into a sorted, de-duplicated list of addresses,
+// skipping anything covered by an exclusion range
+func expandTargets(include, exclude []targetRange, maxHosts int) ([]string, error) {
+	var total uint64
+	for _, r := range include {
+		total += r.size()
+	}
+	if maxHosts > 0 && total > uint64(maxHosts) {
+		return nil, fmt.Errorf("targets expand to %d addresses, more than the limit of %d", total, maxHosts)
+	}
+
+	seen := make(map[uint32]bool, total)
+	var addrs []uint32
+	for _, r := range include {
+		first, last := ipv4ToUint(r.first), ipv4ToUint(r.last)
+		for v := first; ; v++ {
+			if !seen[v] && !excluded(uintToIPv4(v), exclude) {
+				seen[v] = true
+				addrs = append(addrs, v)
+			}
+			if v == last {
+				break
+			}
+		}
+	}
+
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	ips := make([]string, 0, len(addrs))
+	for _, v := range addrs {
+		ips = append(ips, uintToIPv4(v).String())
+	}
+	return ips, nil
+}
+
+// excluded reports whether addr is covered by any exclusion range
+func excluded(addr netip.Addr, exclude []targetRange) bool {
+	for _, r := range exclude {
+		if r.contains(addr) {
+			return true
+		}
+	}
+	return false
+}
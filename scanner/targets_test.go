@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandTargets(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "targets.txt")
+	if err := os.WriteFile(list, []byte("# lab hosts\n10.1.0.7, 10.1.0.5\n\n10.1.0.9-10 # printers\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec, exclude string
+		want          []string
+	}{
+		{"192.168.1.10", "", []string{"192.168.1.10"}},
+		// Network and broadcast addresses are skipped
+		{"192.168.1.0/30", "", []string{"192.168.1.1", "192.168.1.2"}},
+		{"192.168.1.5/30", "", []string{"192.168.1.5", "192.168.1.6"}},
+		// /31 and /32 have neither, so every address is a host
+		{"192.168.1.4/31", "", []string{"192.168.1.4", "192.168.1.5"}},
+		{"192.168.1.4/32", "", []string{"192.168.1.4"}},
+		{"10.0.0.10-10.0.0.13", "", []string{"10.0.0.10", "10.0.0.11", "10.0.0.12", "10.0.0.13"}},
+		{"10.0.0.10-12", "", []string{"10.0.0.10", "10.0.0.11", "10.0.0.12"}},
+		{"10.0.0.10 - 11", "", []string{"10.0.0.10", "10.0.0.11"}},
+		{"10.0.0.255-10.0.1.1", "", []string{"10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		// Sorted and de-duplicated across terms
+		{"10.0.0.3, 10.0.0.1-3,, 10.0.0.2", "", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{"@" + list, "", []string{"10.1.0.5", "10.1.0.7", "10.1.0.9", "10.1.0.10"}},
+		{"@" + list + ",10.1.0.1", "10.1.0.7", []string{"10.1.0.1", "10.1.0.5", "10.1.0.9", "10.1.0.10"}},
+		// An excluded CIDR covers its own network and broadcast addresses
+		{"10.0.0.0/29", "10.0.0.4/30", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{"10.0.0.1-5", "10.0.0.2-3,10.0.0.5", []string{"10.0.0.1", "10.0.0.4"}},
+		{"", "", []string{}},
+	}
+	for _, tt := range tests {
+		got, err := ExpandTargets(tt.spec, tt.exclude, DefaultMaxHosts)
+		if err != nil {
+			t.Errorf("ExpandTargets(%q, %q): %v", tt.spec, tt.exclude, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandTargets(%q, %q) = %v, want %v", tt.spec, tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTargetsMaxHosts(t *testing.T) {
+	// A /24 has 254 hosts once the network and broadcast addresses are dropped
+	if got, err := ExpandTargets("10.0.0.0/24", "", 254); err != nil || len(got) != 254 {
+		t.Errorf("/24 with a limit of 254: %d addresses, %v", len(got), err)
+	}
+	if _, err := ExpandTargets("10.0.0.0/24", "", 253); err == nil {
+		t.Error("/24 accepted with a limit of 253")
+	}
+	// Exclusions do not count against the limit check
+	if _, err := ExpandTargets("10.0.0.0/16", "10.0.0.0/17", 1000); err == nil {
+		t.Error("/16 accepted with a limit of 1000")
+	}
+	if got, err := ExpandTargets("10.0.0.0/16", "", 0); err != nil || len(got) != 65534 {
+		t.Errorf("/16 without a limit: %d addresses, %v", len(got), err)
+	}
+}
+
+func TestExpandTargetsInvalid(t *testing.T) {
+	nested := filepath.Join(t.TempDir(), "nested.txt")
+	if err := os.WriteFile(nested, []byte("10.0.0.1\n@other.txt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(t.TempDir(), "bad.txt")
+	if err := os.WriteFile(bad, []byte("10.0.0.1\n\n10.0.0.300\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec, exclude string
+		want          string // Substring of the error
+	}{
+		{"10.0.0.0/33", "", "invalid CIDR"},
+		{"fd00::/64", "", "only IPv4"},
+		{"10.0.0.256", "", "invalid address"},
+		{"raspberrypi.local", "", "invalid address"},
+		{"10.0.0.20-10", "", "end is before start"},
+		{"10.0.0.10-10.0.0", "", "invalid range"},
+		{"10.0.0.10-x", "", "invalid range"},
+		{"fe80::1-2", "", "invalid range"},
+		{"10.0.0.1", "10.0.0.0/40", "invalid exclusion"},
+		{"@" + filepath.Join(t.TempDir(), "missing.txt"), "", "failed to open target file"},
+		{"@" + nested, "", nested + ":2: nested target files"},
+		{"@" + bad, "", bad + ":3: invalid address"},
+	}
+	for _, tt := range tests {
+		_, err := ExpandTargets(tt.spec, tt.exclude, DefaultMaxHosts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ExpandTargets(%q, %q) error = %v, want %q", tt.spec, tt.exclude, err, tt.want)
+		}
+	}
+}
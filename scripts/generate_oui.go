@@ -3,384 +3,394 @@
 // This script generates the embedded OUI database from external sources.
 // Run with: go run scripts/generate_oui.go
 //
+// In air-gapped environments, point each source at a local copy instead, e.g.
+//
+//	go run scripts/generate_oui.go -offline -mal oui.csv -mam mam.csv -mas oui36.csv -manuf manuf
+//
 // Sources:
-// - IEEE OUI database: https://standards-oui.ieee.org/oui/oui.csv
+// - IEEE MA-L (24-bit) registry: https://standards-oui.ieee.org/oui/oui.csv
+// - IEEE MA-M (28-bit) registry: https://standards-oui.ieee.org/oui28/mam.csv
+// - IEEE MA-S (36-bit) registry: https://standards-oui.ieee.org/oui36/oui36.csv
 // - Wireshark manuf: https://www.wireshark.org/download/automated/data/manuf
+//
+// MA-L entries and the smaller MA-M and MA-S blocks, which carve up a parent MA-L
+// assignment, are written to the binary table data/oui.bin that the data package
+// embeds. The name, SHA-256 and entry count of every input are recorded in
+// data/oui_meta.go.
+//
+// After a change to the category rules in data/category.go, -recategorize applies
+// them to the embedded database without reading any source.
 
 package main
 
 import (
 	"bufio"
-	"encoding/csv"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/james-see/gofindpi/data"
 )
 
 type OUIEntry struct {
 	Prefix       string
+	Bits         int // 24 (MA-L), 28 (MA-M) or 36 (MA-S)
 	Manufacturer string
 	Category     string
 }
 
-// Category mappings based on manufacturer names
-var categoryKeywords = map[string]string{
-	// Raspberry Pi
-	"raspberry":      "Raspberry Pi",
-	"raspberry pi":   "Raspberry Pi",
-	// Network Equipment
-	"cisco":          "Network Equipment",
-	"juniper":        "Network Equipment",
-	"arista":         "Network Equipment",
-	"ubiquiti":       "Network Equipment",
-	"mikrotik":       "Network Equipment",
-	"netgear":        "Network Equipment",
-	"tp-link":        "Network Equipment",
-	"d-link":         "Network Equipment",
-	"linksys":        "Network Equipment",
-	"belkin":         "Network Equipment",
-	"zyxel":          "Network Equipment",
-	"aruba":          "Network Equipment",
-	"fortinet":       "Network Equipment",
-	"palo alto":      "Network Equipment",
-	"sonicwall":      "Network Equipment",
-	"meraki":         "Network Equipment",
-	"ruckus":         "Network Equipment",
-	"extreme":        "Network Equipment",
-	"brocade":        "Network Equipment",
-	"alcatel":        "Network Equipment",
-	// Computers
-	"apple":          "Computer/Phone",
-	"dell":           "Computer",
-	"hp":             "Computer",
-	"hewlett":        "Computer",
-	"lenovo":         "Computer",
-	"intel":          "Computer",
-	"microsoft":      "Computer",
-	"acer":           "Computer",
-	"asus":           "Computer/Network",
-	// Phones/Mobile
-	"samsung":        "Phone/TV",
-	"xiaomi":         "Phone/IoT",
-	"huawei":         "Phone/Network",
-	"oneplus":        "Phone",
-	"oppo":           "Phone",
-	"vivo":           "Phone",
-	"motorola":       "Phone",
-	"nokia":          "Phone",
-	"sony":           "Phone/TV",
-	"google":         "Phone/IoT",
-	// IoT/Smart Home
-	"amazon":         "IoT/Smart Home",
-	"ring":           "IoT/Smart Home",
-	"nest":           "IoT/Smart Home",
-	"philips":        "IoT/Smart Home",
-	"sonos":          "IoT/Audio",
-	"ecobee":         "IoT/Smart Home",
-	"wyze":           "IoT/Smart Home",
-	"tuya":           "IoT/Smart Home",
-	"shelly":         "IoT/Smart Home",
-	"espressif":      "IoT/Embedded",
-	"arduino":        "IoT/Embedded",
-	// TV/Entertainment
-	"lg":             "TV/Display",
-	"vizio":          "TV",
-	"tcl":            "TV",
-	"roku":           "TV/Streaming",
-	// Gaming
-	"nintendo":       "Gaming",
-	"playstation":    "Gaming",
-	"xbox":           "Gaming",
-	"valve":          "Gaming",
-	// Printers
-	"canon":          "Printer/Camera",
-	"epson":          "Printer",
-	"brother":        "Printer",
-	"xerox":          "Printer",
-	"lexmark":        "Printer",
-	// Security/Cameras
-	"hikvision":      "Security Camera",
-	"dahua":          "Security Camera",
-	"axis":           "Security Camera",
-	"lorex":          "Security Camera",
-	"arlo":           "Security Camera",
-	// Storage
-	"synology":       "Storage/NAS",
-	"qnap":           "Storage/NAS",
-	"western digital":"Storage",
-	"seagate":        "Storage",
-	// Virtual/Cloud
-	"vmware":         "Virtual",
-	"xensource":      "Virtual",
-	"parallels":      "Virtual",
+// source is one upstream registry. File overrides URL when set with its flag.
+type source struct {
+	Name string
+	Flag string
+	URL  string
+	File string
+}
+
+// sources are read in priority order: when two sources assign the same prefix, the
+// first one wins
+var sources = []*source{
+	{Name: "IEEE MA-L", Flag: "mal", URL: "https://standards-oui.ieee.org/oui/oui.csv"},
+	{Name: "IEEE MA-M", Flag: "mam", URL: "https://standards-oui.ieee.org/oui28/mam.csv"},
+	{Name: "IEEE MA-S", Flag: "mas", URL: "https://standards-oui.ieee.org/oui36/oui36.csv"},
+	{Name: "Wireshark manuf", Flag: "manuf", URL: "https://www.wireshark.org/download/automated/data/manuf"},
 }
 
-func categorizeManufacturer(name string) string {
-	nameLower := strings.ToLower(name)
-	for keyword, category := range categoryKeywords {
-		if strings.Contains(nameLower, keyword) {
-			return category
+// checkGolden categorizes every manufacturer in the embedded database and compares
+// the result with a golden file listing each categorized name, so changes to the
+// category rules show up as a reviewable diff. It also checks that the categories
+// stored in the database are the ones the rules assign, which fails until the
+// database is regenerated after a rule change. With update set the golden file is
+// rewritten instead.
+func checkGolden(path string, update bool) error {
+	names := make(map[string]bool)
+	var stale []string
+	for _, dbs := range []map[string]data.ManufacturerInfo{data.OUIDatabase(), data.MAMDatabase(), data.MASDatabase()} {
+		for prefix, info := range dbs {
+			names[info.Name] = true
+			if want := data.CategorizeManufacturer(info.Name); info.Category != want {
+				stale = append(stale, fmt.Sprintf("%s %q is %q, rules say %q", prefix, info.Name, info.Category, want))
+			}
 		}
 	}
-	return "Unknown"
-}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
 
-func normalizeOUI(oui string) string {
-	// Remove common separators and convert to lowercase
-	oui = strings.ToLower(oui)
-	oui = strings.ReplaceAll(oui, "-", "")
-	oui = strings.ReplaceAll(oui, ":", "")
-	oui = strings.ReplaceAll(oui, ".", "")
-	
-	// Take only first 6 hex chars (3 bytes)
-	if len(oui) >= 6 {
-		oui = oui[:6]
+	var b strings.Builder
+	b.WriteString("# Categories assigned by scripts/generate_oui.go to the embedded vendor list.\n")
+	b.WriteString("# Names that stay \"Unknown\" are omitted. Regenerate with: make update-golden\n")
+	for _, name := range sorted {
+		if category := data.CategorizeManufacturer(name); category != "Unknown" {
+			fmt.Fprintf(&b, "%s\t%s\n", category, name)
+		}
 	}
-	
-	// Format as xx:xx:xx
-	if len(oui) == 6 {
-		return fmt.Sprintf("%s:%s:%s", oui[0:2], oui[2:4], oui[4:6])
+
+	if update {
+		return os.WriteFile(path, []byte(b.String()), 0o644)
 	}
-	return oui
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if string(want) != b.String() {
+		return fmt.Errorf("categories differ from %s; review the change and run make update-golden", path)
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		return fmt.Errorf("%d embedded categories differ from the rules, e.g.\n  %s\nregenerate the database with make update-oui, or apply the rules alone with make recategorize-oui",
+			len(stale), strings.Join(stale[:min(len(stale), 10)], "\n  "))
+	}
+	return nil
 }
 
-func downloadIEEEOUI() ([]OUIEntry, error) {
-	fmt.Println("Downloading IEEE OUI database...")
-	
-	resp, err := http.Get("https://standards-oui.ieee.org/oui/oui.csv")
+// recategorizeDatabase rewrites the categories of the embedded database with the
+// current rules, keeping its prefixes and names, and reports how many changed. It
+// finishes a rule change without downloading the registries again.
+func recategorizeDatabase(outputPath string) (int, error) {
+	changed := 0
+	dbs := map[string]map[string]data.ManufacturerInfo{
+		data.RegistryMAL: data.OUIDatabase(),
+		data.RegistryMAM: data.MAMDatabase(),
+		data.RegistryMAS: data.MASDatabase(),
+	}
+	for _, db := range dbs {
+		for prefix, info := range db {
+			if category := data.CategorizeManufacturer(info.Name); category != info.Category {
+				info.Category = category
+				db[prefix] = info
+				changed++
+			}
+		}
+	}
+
+	blob, err := data.EncodeDatabase(dbs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download IEEE OUI: %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	return changed, os.WriteFile(outputPath, blob, 0o644)
+}
+
+// loadSource reads a source from its local file or downloads it, parses it and
+// records its provenance
+func loadSource(src *source, offline bool) ([]OUIEntry, data.SourceInfo, error) {
+	info := data.SourceInfo{Name: src.Name, Location: src.URL}
+
+	var raw []byte
+	var err error
+	switch {
+	case src.File != "":
+		fmt.Printf("Reading %s from %s...\n", src.Name, src.File)
+		info.Location = src.File
+		raw, err = os.ReadFile(src.File)
+	case offline:
+		return nil, info, fmt.Errorf("no local file given (-%s) and downloads are disabled", src.Flag)
+	default:
+		fmt.Printf("Downloading %s...\n", src.Name)
+		raw, err = download(src.URL)
 	}
-	
-	reader := csv.NewReader(resp.Body)
-	var entries []OUIEntry
-	
-	// Skip header
-	_, err = reader.Read()
 	if err != nil {
-		return nil, err
+		return nil, info, err
 	}
-	
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			continue // Skip malformed lines
-		}
-		
-		if len(record) < 3 {
-			continue
-		}
-		
-		// IEEE CSV format: Registry, Assignment, Organization Name, ...
-		oui := normalizeOUI(record[1])
-		manufacturer := strings.TrimSpace(record[2])
-		
-		if oui == "" || manufacturer == "" {
-			continue
-		}
-		
-		entry := OUIEntry{
-			Prefix:       oui,
-			Manufacturer: manufacturer,
-			Category:     categorizeManufacturer(manufacturer),
-		}
-		entries = append(entries, entry)
+
+	sum := sha256.Sum256(raw)
+	info.SHA256 = hex.EncodeToString(sum[:])
+
+	// Both IEEE CSV and manuf files are recognized by content
+	parsed, err := data.ParseOUIFile(bytes.NewReader(raw))
+	if err != nil {
+		return nil, info, err
 	}
-	
-	fmt.Printf("Downloaded %d entries from IEEE\n", len(entries))
-	return entries, nil
+
+	entries := make([]OUIEntry, 0, len(parsed))
+	for _, e := range parsed {
+		entries = append(entries, OUIEntry{
+			Prefix:       e.Prefix,
+			Bits:         e.Bits,
+			Manufacturer: e.Name,
+			Category:     data.CategorizeManufacturer(e.Name),
+		})
+	}
+	info.Entries = len(entries)
+
+	fmt.Printf("Read %d entries from %s\n", len(entries), src.Name)
+	return entries, info, nil
 }
 
-func downloadWiresharkManuf() ([]OUIEntry, error) {
-	fmt.Println("Downloading Wireshark manuf database...")
-	
-	resp, err := http.Get("https://www.wireshark.org/download/automated/data/manuf")
+// download fetches a URL into memory
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download Wireshark manuf: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
 	}
-	
-	var entries []OUIEntry
-	scanner := bufio.NewScanner(resp.Body)
-	
-	// Pattern: OUI<tab>Short Name<tab>Long Name
-	ouiPattern := regexp.MustCompile(`^([0-9A-Fa-f]{2}:[0-9A-Fa-f]{2}:[0-9A-Fa-f]{2})\s+(\S+)\s*(.*)$`)
-	
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "#") || line == "" {
-			continue
-		}
-		
-		matches := ouiPattern.FindStringSubmatch(line)
-		if matches == nil {
+	return io.ReadAll(resp.Body)
+}
+
+// countConflicts counts prefixes assigned more than once with different names. The
+// first assignment is kept, so each conflict is resolved in favor of the earlier source.
+func countConflicts(entries []OUIEntry) int {
+	first := make(map[string]string)
+	conflicts := 0
+	for _, e := range entries {
+		key := fmt.Sprintf("%s/%d", e.Prefix, e.Bits)
+		name, ok := first[key]
+		if !ok {
+			first[key] = e.Manufacturer
 			continue
 		}
-		
-		oui := strings.ToLower(matches[1])
-		shortName := matches[2]
-		longName := strings.TrimSpace(matches[3])
-		
-		manufacturer := longName
-		if manufacturer == "" {
-			manufacturer = shortName
+		if !strings.EqualFold(name, e.Manufacturer) {
+			conflicts++
 		}
-		
-		entry := OUIEntry{
-			Prefix:       oui,
-			Manufacturer: manufacturer,
-			Category:     categorizeManufacturer(manufacturer),
-		}
-		entries = append(entries, entry)
 	}
-	
-	fmt.Printf("Downloaded %d entries from Wireshark\n", len(entries))
-	return entries, nil
+	return conflicts
 }
 
-func generateGoFile(entries []OUIEntry, outputPath string) error {
-	// Deduplicate by OUI prefix
+// uniqueEntries returns the entries with the given prefix length, deduplicated
+// (first source wins) and sorted by prefix
+func uniqueEntries(entries []OUIEntry, bits int) []OUIEntry {
 	seen := make(map[string]bool)
 	var unique []OUIEntry
 	for _, e := range entries {
-		if !seen[e.Prefix] {
+		if e.Bits == bits && !seen[e.Prefix] {
 			seen[e.Prefix] = true
 			unique = append(unique, e)
 		}
 	}
-	
-	// Sort by prefix
+
 	sort.Slice(unique, func(i, j int) bool {
 		return unique[i].Prefix < unique[j].Prefix
 	})
-	
+	return unique
+}
+
+// generateDatabaseFile writes the deduplicated entries of every registry as the
+// binary database embedded by the data package
+func generateDatabaseFile(entries []OUIEntry, outputPath string) error {
+	dbs := map[string]map[string]data.ManufacturerInfo{}
+	for registry, bits := range map[string]int{data.RegistryMAL: 24, data.RegistryMAM: 28, data.RegistryMAS: 36} {
+		db := make(map[string]data.ManufacturerInfo)
+		for _, e := range uniqueEntries(entries, bits) {
+			name := strings.ReplaceAll(e.Manufacturer, "\n", " ")
+			name = strings.ReplaceAll(name, "\r", "")
+			db[e.Prefix] = data.ManufacturerInfo{Name: name, Category: e.Category}
+		}
+		dbs[registry] = db
+	}
+
+	blob, err := data.EncodeDatabase(dbs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, blob, 0o644)
+}
+
+// generateMetadataFile records where the database came from
+func generateMetadataFile(meta data.DatabaseMetadata, outputPath string) error {
 	file, err := os.Create(outputPath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	
+
 	w := bufio.NewWriter(file)
-	
-	// Write header
+
 	fmt.Fprintf(w, `// Code generated by scripts/generate_oui.go; DO NOT EDIT.
-// Generated: %s
-// Total entries: %d
 
 package data
 
-// ManufacturerInfo contains information about a device manufacturer
-type ManufacturerInfo struct {
-	Name     string
-	Category string
+func init() {
+	Metadata = DatabaseMetadata{
+		Generated: %q,
+		Entries:   %d,
+		Conflicts: %d,
+		Sources: []SourceInfo{
+`, meta.Generated, meta.Entries, meta.Conflicts)
+	for _, src := range meta.Sources {
+		fmt.Fprintf(w, "\t\t\t{Name: %q, Location: %q, SHA256: %q, Entries: %d},\n",
+			src.Name, src.Location, src.SHA256, src.Entries)
+	}
+	fmt.Fprint(w, "\t\t},\n\t}\n}\n")
+
+	return w.Flush()
 }
 
-// OUIDatabase maps MAC address prefixes (OUI) to manufacturer information
-// Format: "xx:xx:xx" -> ManufacturerInfo
-var OUIDatabase = map[string]ManufacturerInfo{
-`, time.Now().Format(time.RFC3339), len(unique))
-	
-	for _, entry := range unique {
-		// Escape quotes in manufacturer names
-		name := strings.ReplaceAll(entry.Manufacturer, `"`, `\"`)
-		name = strings.ReplaceAll(name, "\n", " ")
-		name = strings.ReplaceAll(name, "\r", "")
-		
-		fmt.Fprintf(w, "\t%q: {Name: %q, Category: %q},\n", 
-			entry.Prefix, name, entry.Category)
+func main() {
+	golden := flag.String("golden", "", "check categorization of the embedded vendor list against this golden file and exit")
+	updateGolden := flag.Bool("update-golden", false, "rewrite the -golden file instead of checking it")
+	recategorize := flag.Bool("recategorize", false, "rewrite the categories of the embedded database with the current rules and exit; no source is read")
+	offline := flag.Bool("offline", false, "never download; sources without a local file are skipped")
+	for _, src := range sources {
+		flag.StringVar(&src.File, src.Flag, "", "read "+src.Name+" from this local file instead of "+src.URL)
 	}
-	
-	fmt.Fprintln(w, "}")
-	
-	// Add Raspberry Pi specific entries to ensure they're included
-	fmt.Fprintln(w, `
-// RaspberryPiOUIs contains all known Raspberry Pi MAC prefixes
-var RaspberryPiOUIs = []string{
-	"b8:27:eb", // Original Raspberry Pi Foundation
-	"dc:a6:32", // Raspberry Pi Trading Ltd
-	"e4:5f:01", // Raspberry Pi 4 and newer
-	"28:cd:c1", // Raspberry Pi 400 and some Pi 4
-	"d8:3a:dd", // Some Raspberry Pi models
-	"2c:cf:67", // Raspberry Pi 5 and newer models
-}
+	flag.Parse()
 
-// IsRaspberryPiOUI checks if a MAC prefix belongs to a Raspberry Pi
-func IsRaspberryPiOUI(prefix string) bool {
-	for _, oui := range RaspberryPiOUIs {
-		if prefix == oui {
-			return true
+	if *golden != "" {
+		if err := checkGolden(*golden, *updateGolden); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
+		fmt.Println("Categories match", *golden)
+		return
+	}
+	if *recategorize {
+		changed, err := recategorizeDatabase("data/oui.bin")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Recategorized %d entries in data/oui.bin\n", changed)
+		return
 	}
-	return false
-}`)
-	
-	return w.Flush()
-}
 
-func main() {
 	fmt.Println("OUI Database Generator")
 	fmt.Println("======================")
 	
-	var allEntries []OUIEntry
-	
-	// Try IEEE first
-	ieeeEntries, err := downloadIEEEOUI()
-	if err != nil {
-		fmt.Printf("Warning: Could not download IEEE OUI: %v\n", err)
-	} else {
-		allEntries = append(allEntries, ieeeEntries...)
-	}
+	var (
+		allEntries []OUIEntry
+		meta       data.DatabaseMetadata
+	)
 	
-	// Then Wireshark for additional entries
-	wsEntries, err := downloadWiresharkManuf()
-	if err != nil {
-		fmt.Printf("Warning: Could not download Wireshark manuf: %v\n", err)
-	} else {
-		allEntries = append(allEntries, wsEntries...)
+	// IEEE registries first, then Wireshark for additional entries
+	for _, src := range sources {
+		entries, info, err := loadSource(src, *offline)
+		if err != nil {
+			fmt.Printf("Warning: Could not read %s: %v\n", src.Name, err)
+			continue
+		}
+		// A source that parses but yields nothing is the wrong file or a changed format
+		if len(entries) == 0 {
+			fmt.Printf("Error: No entries in %s (%s)\n", src.Name, info.Location)
+			os.Exit(1)
+		}
+		allEntries = append(allEntries, entries...)
+		meta.Sources = append(meta.Sources, info)
 	}
 	
 	if len(allEntries) == 0 {
-		fmt.Println("Error: No OUI entries downloaded. Check network connection.")
+		fmt.Println("Error: No OUI entries read. Check network connection or pass local files.")
 		os.Exit(1)
 	}
 	
 	// Add manual Raspberry Pi entries to ensure they're always present
 	piEntries := []OUIEntry{
-		{Prefix: "b8:27:eb", Manufacturer: "Raspberry Pi Foundation", Category: "Raspberry Pi"},
-		{Prefix: "dc:a6:32", Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
-		{Prefix: "e4:5f:01", Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
-		{Prefix: "28:cd:c1", Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
-		{Prefix: "d8:3a:dd", Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
-		{Prefix: "2c:cf:67", Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
+		{Prefix: "b8:27:eb", Bits: 24, Manufacturer: "Raspberry Pi Foundation", Category: "Raspberry Pi"},
+		{Prefix: "dc:a6:32", Bits: 24, Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
+		{Prefix: "e4:5f:01", Bits: 24, Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
+		{Prefix: "28:cd:c1", Bits: 24, Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
+		{Prefix: "d8:3a:dd", Bits: 24, Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
+		{Prefix: "2c:cf:67", Bits: 24, Manufacturer: "Raspberry Pi Trading Ltd", Category: "Raspberry Pi"},
 	}
 	allEntries = append(piEntries, allEntries...) // Pi entries first for priority
 	
 	fmt.Printf("\nTotal entries collected: %d\n", len(allEntries))
+
+	// Never embed an empty registry: lookups would silently fall back to the parent OUI
+	for _, reg := range []struct {
+		name string
+		bits int
+	}{{data.RegistryMAL, 24}, {data.RegistryMAM, 28}, {data.RegistryMAS, 36}} {
+		if len(uniqueEntries(allEntries, reg.bits)) == 0 {
+			fmt.Printf("Error: No %s entries read. Check its source or pass a local file.\n", reg.name)
+			os.Exit(1)
+		}
+	}
 	
-	outputPath := "data/oui.go"
-	if err := generateGoFile(allEntries, outputPath); err != nil {
-		fmt.Printf("Error generating Go file: %v\n", err)
+	outputPath := "data/oui.bin"
+	if err := generateDatabaseFile(allEntries, outputPath); err != nil {
+		fmt.Printf("Error generating database: %v\n", err)
 		os.Exit(1)
 	}
-	
+
 	fmt.Printf("Generated: %s\n", outputPath)
+
+	meta.Generated = time.Now().Format(time.RFC3339)
+	meta.Conflicts = countConflicts(allEntries)
+	for _, bits := range []int{24, 28, 36} {
+		meta.Entries += len(uniqueEntries(allEntries, bits))
+	}
+	metaPath := "data/oui_meta.go"
+	if err := generateMetadataFile(meta, metaPath); err != nil {
+		fmt.Printf("Error generating Go file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Generated: %s (%d conflicts resolved)\n", metaPath, meta.Conflicts)
 	fmt.Println("Done!")
 }
 